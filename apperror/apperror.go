@@ -0,0 +1,131 @@
+// Package apperror defines the errors the service reports to clients. Each
+// carries a stable, machine-readable code that decides the HTTP status; the
+// error middleware turns them into the usual response envelope.
+package apperror
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Code identifies a kind of failure. Codes are part of the API and must not
+// change once released.
+type Code string
+
+const (
+	CodeValidation       Code = "validation_failed"
+	CodeUnauthorized     Code = "unauthorized"
+	CodeForbidden        Code = "forbidden"
+	CodeNotFound         Code = "not_found"
+	CodeConflict         Code = "conflict"
+	CodeGone             Code = "gone"
+	CodePayloadTooLarge  Code = "payload_too_large"
+	CodeUnsupportedType  Code = "unsupported_type"
+	CodeUnprocessable    Code = "unprocessable"
+	CodeRateLimited      Code = "rate_limited"
+	CodeQuotaExceeded    Code = "quota_exceeded"
+	CodeConversionFailed Code = "conversion_failed"
+	CodeInternal         Code = "internal"
+)
+
+var statuses = map[Code]int{
+	CodeValidation:       http.StatusBadRequest,
+	CodeUnauthorized:     http.StatusUnauthorized,
+	CodeForbidden:        http.StatusForbidden,
+	CodeNotFound:         http.StatusNotFound,
+	CodeConflict:         http.StatusConflict,
+	CodeGone:             http.StatusGone,
+	CodePayloadTooLarge:  http.StatusRequestEntityTooLarge,
+	CodeUnsupportedType:  http.StatusUnsupportedMediaType,
+	CodeUnprocessable:    http.StatusUnprocessableEntity,
+	CodeRateLimited:      http.StatusTooManyRequests,
+	CodeQuotaExceeded:    http.StatusTooManyRequests,
+	CodeConversionFailed: http.StatusBadGateway,
+	CodeInternal:         http.StatusInternalServerError,
+}
+
+// Status returns the HTTP status reported for code.
+func Status(code Code) int {
+	if status, ok := statuses[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is a failure reported to the client. Message is a short summary
+// shown as the response status; the cause, when there is one, is the detail.
+type Error struct {
+	Code    Code
+	Message string
+	Err     error
+	// Details is returned as the response data, e.g. per-field problems.
+	Details interface{}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns an error with code and message and no cause.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap returns err as an *Error. An err that already carries a code keeps it;
+// anything else becomes an internal error summarised by message.
+func Wrap(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+	return &Error{Code: CodeInternal, Message: message, Err: err}
+}
+
+// As returns the *Error in err's chain, treating anything else as internal.
+func As(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return &Error{Code: CodeInternal, Message: "Internal server error", Err: err}
+}
+
+// CodeOf returns the code of err, CodeInternal when it carries none.
+func CodeOf(err error) Code {
+	return As(err).Code
+}
+
+func NotFound(message string) *Error {
+	return New(CodeNotFound, message)
+}
+
+func Validation(message string, err error) *Error {
+	return &Error{Code: CodeValidation, Message: message, Err: err}
+}
+
+func Conflict(message string, err error) *Error {
+	return &Error{Code: CodeConflict, Message: message, Err: err}
+}
+
+func UnsupportedType(message string, err error) *Error {
+	return &Error{Code: CodeUnsupportedType, Message: message, Err: err}
+}
+
+func ConversionFailed(err error) *Error {
+	return &Error{Code: CodeConversionFailed, Message: "Failed to render document", Err: err}
+}
+
+// WrapAs returns err as an error with code, summarised by message.
+func WrapAs(err error, code Code, message string) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
@@ -0,0 +1,29 @@
+// Package audit carries who performed a request, and from where, through
+// context.Context so the use case layer can record it.
+package audit
+
+import "context"
+
+// Actor identifies the caller of a request for the audit log.
+type Actor struct {
+	Subject   string
+	IP        string
+	UserAgent string
+}
+
+type contextKey struct{}
+
+// WithActor returns a context recording actor as the caller.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, contextKey{}, actor)
+}
+
+// ActorFromContext returns the caller of ctx. Work not started by a request,
+// such as scheduled purges, is attributed to "system".
+func ActorFromContext(ctx context.Context) Actor {
+	actor, ok := ctx.Value(contextKey{}).(Actor)
+	if !ok {
+		return Actor{Subject: "system"}
+	}
+	return actor
+}
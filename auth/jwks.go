@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwks caches the RSA keys of a JSON Web Key Set. Keys are refreshed after
+// the refresh interval and, at most once a minute, when a token names a key
+// that is not in the cache, which covers key rotation at the issuer.
+type jwks struct {
+	source  string
+	refresh time.Duration
+	client  *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func newJWKS(source string, refresh time.Duration) *jwks {
+	if refresh <= 0 {
+		refresh = 10 * time.Minute
+	}
+	return &jwks{
+		source:  source,
+		refresh: refresh,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (j *jwks) key(kid string) (*rsa.PublicKey, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	stale := time.Since(j.fetchedAt) > j.refresh
+	_, known := j.keys[kid]
+	if stale || !known && time.Since(j.fetchedAt) > time.Minute {
+		if err := j.load(); err != nil && j.keys == nil {
+			return nil, err
+		}
+	}
+
+	if key, ok := j.keys[kid]; ok {
+		return key, nil
+	}
+	// A set with a single key may be used by tokens without a kid.
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (j *jwks) load() error {
+	j.fetchedAt = time.Now()
+
+	body, err := j.read()
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS: %w", err)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &set); err != nil {
+		return fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := parseRSAKey(k)
+		if err != nil {
+			return err
+		}
+		keys[k.Kid] = key
+	}
+	j.keys = keys
+	return nil
+}
+
+func (j *jwks) read() ([]byte, error) {
+	if !strings.HasPrefix(j.source, "http://") && !strings.HasPrefix(j.source, "https://") {
+		return os.ReadFile(j.source)
+	}
+
+	resp, err := j.client.Get(j.source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func parseRSAKey(k jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus for key %q: %w", k.Kid, err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent for key %q: %w", k.Kid, err)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrInvalidToken is returned for tokens that fail verification.
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrInvalidApiKey is returned for unknown, revoked or expired API keys.
+	ErrInvalidApiKey = errors.New("invalid API key")
+)
+
+// TokenVerifier turns a bearer token into the principal it was issued to.
+type TokenVerifier interface {
+	Verify(token string) (*Principal, error)
+}
+
+// ApiKeyVerifier turns an X-API-Key header into the principal that owns it.
+type ApiKeyVerifier interface {
+	VerifyApiKey(ctx context.Context, key string) (*Principal, error)
+}
+
+type jwtVerifier struct {
+	method      string
+	keyfunc     jwt.Keyfunc
+	options     []jwt.ParserOption
+	tenantClaim string
+}
+
+// NewJWTVerifier verifies HS256 tokens with a shared secret or RS256 tokens
+// against a JWKS read from a local file or URL, and checks the issuer and
+// audience when they are configured.
+func NewJWTVerifier(conf *config.JWT) (TokenVerifier, error) {
+	if conf == nil {
+		return nil, errors.New("auth.jwt must be configured")
+	}
+
+	v := &jwtVerifier{
+		method:      strings.ToUpper(conf.Algorithm),
+		tenantClaim: conf.TenantClaim,
+	}
+	if v.tenantClaim == "" {
+		v.tenantClaim = "tenant_id"
+	}
+
+	switch v.method {
+	case "", "HS256":
+		if err := config.CheckSecret("auth.jwt.secret", conf.Secret); err != nil {
+			return nil, err
+		}
+		v.method = "HS256"
+		secret := []byte(conf.Secret)
+		v.keyfunc = func(*jwt.Token) (interface{}, error) { return secret, nil }
+	case "RS256":
+		source := conf.JwksUrl
+		if source == "" {
+			source = conf.JwksFile
+		}
+		if source == "" {
+			return nil, errors.New("auth.jwt.jwksurl or auth.jwt.jwksfile is required for RS256")
+		}
+		keys := newJWKS(source, conf.JwksRefresh)
+		v.keyfunc = func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return keys.key(kid)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported auth.jwt.algorithm %q", conf.Algorithm)
+	}
+
+	v.options = []jwt.ParserOption{
+		jwt.WithValidMethods([]string{v.method}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if conf.Issuer != "" {
+		v.options = append(v.options, jwt.WithIssuer(conf.Issuer))
+	}
+	if conf.Audience != "" {
+		v.options = append(v.options, jwt.WithAudience(conf.Audience))
+	}
+	return v, nil
+}
+
+func (v *jwtVerifier) Verify(tokenString string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(tokenString, claims, v.keyfunc, v.options...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+	tenantID, _ := claims[v.tenantClaim].(string)
+
+	return &Principal{
+		Subject:  subject,
+		TenantID: tenantID,
+		Scopes:   scopesFromClaims(claims),
+		Method:   "jwt",
+		Claims:   claims,
+	}, nil
+}
+
+// scopesFromClaims accepts both the OAuth "scope" string and a "scopes" array.
+func scopesFromClaims(claims jwt.MapClaims) []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+
+	raw, _ := claims["scopes"].([]interface{})
+	scopes := make([]string, 0, len(raw))
+	for _, s := range raw {
+		if scope, ok := s.(string); ok {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
@@ -0,0 +1,34 @@
+package auth
+
+import "github.com/gin-gonic/gin"
+
+const principalKey = "auth.principal"
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject  string
+	TenantID string
+	Scopes   []string
+	// Owner is the subject an API key acts for. The key holds at most the
+	// permissions of its owner's roles.
+	Owner string
+	// Method records how the caller authenticated, e.g. "jwt".
+	Method string
+	Claims map[string]interface{}
+}
+
+// SetPrincipal attaches the authenticated caller to the request.
+func SetPrincipal(ctx *gin.Context, principal *Principal) {
+	ctx.Set(principalKey, principal)
+}
+
+// GetPrincipal returns the authenticated caller, or nil for anonymous
+// requests.
+func GetPrincipal(ctx *gin.Context) *Principal {
+	value, ok := ctx.Get(principalKey)
+	if !ok {
+		return nil
+	}
+	principal, _ := value.(*Principal)
+	return principal
+}
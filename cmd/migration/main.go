@@ -1,19 +1,139 @@
+// Command migration manages the database schema.
+//
+//	go run ./cmd/migration up               apply pending migrations
+//	go run ./cmd/migration down 1           revert the last migration
+//	go run ./cmd/migration status           list migrations and their state
+//	go run ./cmd/migration create add_foo   add an empty migration
+//	go run ./cmd/migration force 20261019000000
+//
+// Without a subcommand it runs up. Migrations are embedded at build time, so
+// a migration made with create is applied by a rebuilt binary.
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
 	"github.com/IlhamSetiaji/report-converter/config"
 	"github.com/IlhamSetiaji/report-converter/database"
-	"github.com/IlhamSetiaji/report-converter/entity"
 	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/migration"
+	"github.com/sirupsen/logrus"
 )
 
 func main() {
+	var dir string
+	flag.StringVar(&dir, "dir", "migration/migrations", "directory create writes new migrations to")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: migration [-dir path] [up | down N | status | create NAME | force VERSION]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	command, args := "up", flag.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
 	config := config.GetConfig()
-	logger := logger.NewLogger()
+	logger := logger.NewLogger(config)
+	log := logger.GetLogger()
+
+	// create only writes files and works without a database.
+	if command == "create" {
+		if len(args) != 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		upPath, downPath, err := migration.Create(dir, args[0], time.Now())
+		if err != nil {
+			log.WithError(err).Fatal("Failed to create migration")
+		}
+		log.WithField("up", upPath).WithField("down", downPath).Info("Created migration")
+		return
+	}
+
 	db := database.NewPostgresDatabase(config)
+	defer db.Close()
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to load migrations")
+	}
+	ctx := context.Background()
+
+	switch {
+	case command == "up" && len(args) == 0:
+		applied, err := migrator.Up(ctx)
+		logMigrations(log, applied, "Applied migration")
+		if err != nil {
+			log.WithError(err).Fatal("Failed to apply migrations")
+		}
+		if len(applied) == 0 {
+			log.Info("Database schema is up to date")
+		}
+
+	case command == "down" && len(args) == 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			log.WithError(err).Fatal("Invalid number of migrations")
+		}
+		reverted, err := migrator.Down(ctx, n)
+		logMigrations(log, reverted, "Reverted migration")
+		if err != nil {
+			log.WithError(err).Fatal("Failed to revert migrations")
+		}
+
+	case command == "status" && len(args) == 0:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to read migration status")
+		}
+		printStatus(statuses)
+
+	case command == "force" && len(args) == 1:
+		version, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			log.WithError(err).Fatal("Invalid migration version")
+		}
+		if err := migrator.Force(ctx, version); err != nil {
+			log.WithError(err).Fatal("Failed to force migration version")
+		}
+		log.WithField("version", version).Info("Forced migration version")
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func logMigrations(log *logrus.Logger, migrations []migration.Migration, message string) {
+	for _, m := range migrations {
+		log.WithField("version", m.Version).WithField("name", m.Name).Info(message)
+	}
+}
 
-	// Initialize the database connection
-	if err := db.GetDb().AutoMigrate(&entity.Template{}); err != nil {
-		logger.GetLogger().Fatal("Failed to migrate database", err)
+func printStatus(statuses []migration.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", ""
+		switch {
+		case status.Dirty:
+			state = "dirty"
+		case status.Missing:
+			state = "applied, no file"
+		case status.Applied:
+			state = "applied"
+		}
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
 	}
+	w.Flush()
 }
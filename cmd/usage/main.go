@@ -0,0 +1,63 @@
+// Command usage exports aggregated rendering usage for charging.
+//
+//	go run ./cmd/usage -period month -from 2024-01-01 -to 2024-03-31 -format csv -out usage.csv
+//
+// Without -tenant the report covers every tenant.
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/IlhamSetiaji/report-converter/database"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/repository"
+	"github.com/IlhamSetiaji/report-converter/request"
+	"github.com/IlhamSetiaji/report-converter/tenant"
+	"github.com/IlhamSetiaji/report-converter/usecase"
+	"github.com/IlhamSetiaji/report-converter/validator"
+)
+
+func main() {
+	var req request.UsageReportRequest
+	var tenantID, out string
+	flag.StringVar(&req.Period, "period", "day", "aggregate per day or month")
+	flag.StringVar(&req.From, "from", "", "first day to include, YYYY-MM-DD")
+	flag.StringVar(&req.To, "to", "", "last day to include, YYYY-MM-DD")
+	flag.BoolVar(&req.IncludePreview, "include-preview", false, "include preview renders")
+	flag.StringVar(&req.Format, "format", "csv", "csv or json")
+	flag.StringVar(&tenantID, "tenant", "", "only report this tenant")
+	flag.StringVar(&out, "out", "", "write to this file instead of stdout")
+	flag.Parse()
+
+	config := config.GetConfig()
+	logger := logger.NewLogger(config)
+	db := database.NewPostgresDatabase(config)
+
+	if err := validator.NewValidatorV10(config).GetValidator().Struct(req); err != nil {
+		logger.GetLogger().WithError(err).Fatal("Invalid arguments")
+	}
+
+	ctx := tenant.WithAllTenants(context.Background())
+	if tenantID != "" {
+		ctx = tenant.WithID(context.Background(), tenantID)
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "" {
+		file, err := os.Create(out)
+		if err != nil {
+			logger.GetLogger().WithError(err).Fatal("Failed to create output file")
+		}
+		defer file.Close()
+		w = file
+	}
+
+	usageUseCase := usecase.NewUsageUseCase(repository.NewUsageRepository(db, logger))
+	if err := usageUseCase.ExportUsage(ctx, &req, w); err != nil {
+		logger.GetLogger().WithError(err).Fatal("Failed to export usage")
+	}
+}
@@ -3,21 +3,41 @@ package config
 import (
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type (
 	Config struct {
-		Server *Server
-		Db     *Db
+		Server    *Server
+		Db        *Db
+		Storage   *Storage
+		Download  *Download
+		Upload    *Upload
+		Auth      *Auth
+		RateLimit *RateLimit
+		Quota     *Quota
+		Audit     *Audit
+		Converter *Converter
+		Metrics   *Metrics
+		Tracing   *Tracing
+		Log       *Log
+		Health    *Health
 	}
 
 	Server struct {
-		Port int
-		Name string
-		Url  string
-		Os   string
+		Port            int
+		Name            string
+		Url             string
+		Os              string
+		ShutdownTimeout time.Duration
+		DrainDelay      time.Duration
+	}
+
+	Health struct {
+		Timeout          time.Duration
+		SelfTestInterval time.Duration
 	}
 
 	Db struct {
@@ -28,9 +48,111 @@ type (
 		DBName   string
 		SSLMode  string
 		TimeZone string
+		// CheckMigrations refuses to start the server while migrations are
+		// pending.
+		CheckMigrations bool
+	}
+
+	Storage struct {
+		Driver         string
+		LocalRoot      string
+		S3             *S3
+		TrashRetention time.Duration
+		PurgeInterval  time.Duration
+	}
+
+	Download struct {
+		SigningKey string
+		Expiry     time.Duration
+	}
+
+	Upload struct {
+		MaxSize             int64
+		MaxEntries          int
+		MaxUncompressedSize int64
+		MaxCompressionRatio int
+	}
+
+	Auth struct {
+		// Enabled is a pointer so that a missing setting can be told apart
+		// from an explicit false; see Disabled.
+		Enabled *bool
+		Jwt     *JWT
+		// Admins are subjects holding the admin role in every tenant, which
+		// is how the first role assignments are made.
+		Admins []string
+	}
+
+	JWT struct {
+		Algorithm   string
+		Secret      string
+		JwksUrl     string
+		JwksFile    string
+		JwksRefresh time.Duration
+		Issuer      string
+		Audience    string
+		TenantClaim string
+	}
+
+	// RateLimit is a token bucket per client: Rate tokens are added per
+	// second up to Burst, and each request takes one.
+	RateLimit struct {
+		Enabled bool
+		Backend string
+		Rate    float64
+		Burst   int
+	}
+
+	// Quota caps renders per tenant; zero means unlimited.
+	Quota struct {
+		Daily   int
+		Monthly int
+	}
+
+	Audit struct {
+		// HashKey keys the HMAC recorded instead of render payloads, so a
+		// hash cannot be matched against guessed personal data.
+		HashKey string
+	}
+
+	Converter struct {
+		MaxConcurrent int
+		Timeout       time.Duration
+	}
+
+	Metrics struct {
+		Enabled bool
+	}
+
+	Log struct {
+		Level string
+	}
+
+	Tracing struct {
+		Enabled     bool
+		Exporter    string
+		Endpoint    string
+		File        string
+		SampleRatio float64
+		ServiceName string
+	}
+
+	S3 struct {
+		Endpoint  string
+		AccessKey string
+		SecretKey string
+		Bucket    string
+		Region    string
+		UseSSL    bool
 	}
 )
 
+// Disabled reports whether authentication was switched off explicitly with
+// auth.enabled: false. A missing auth section or setting leaves it on.
+func (a *Auth) Disabled() bool {
+	return a != nil && a.Enabled != nil && !*a.Enabled
+}
+
 var (
 	once           sync.Once
 	configInstance *Config
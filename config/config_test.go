@@ -0,0 +1,21 @@
+package config
+
+import "testing"
+
+func TestAuthDisabledOnlyWhenSetToFalse(t *testing.T) {
+	enabled, disabled := true, false
+	for _, tc := range []struct {
+		name string
+		auth *Auth
+		want bool
+	}{
+		{"missing section", nil, false},
+		{"missing setting", &Auth{}, false},
+		{"enabled", &Auth{Enabled: &enabled}, false},
+		{"disabled", &Auth{Enabled: &disabled}, true},
+	} {
+		if got := tc.auth.Disabled(); got != tc.want {
+			t.Errorf("%s: Disabled() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MinSecretLength is the shortest signing secret accepted, in bytes. It
+// matches the output size of HMAC-SHA256.
+const MinSecretLength = 32
+
+// placeholderSecrets are fragments of the sample values found in example
+// configs; a secret containing one was copied rather than generated.
+var placeholderSecrets = []string{"change_me", "changeme", "replace_me", "your_secret", "secret_here"}
+
+// CheckSecret reports why secret cannot be used to sign tokens or links, or
+// nil if it can. name is the configuration key, used in the error.
+func CheckSecret(name string, secret string) error {
+	if secret == "" {
+		return fmt.Errorf("%s must be configured", name)
+	}
+	lower := strings.ToLower(secret)
+	for _, placeholder := range placeholderSecrets {
+		if strings.Contains(lower, placeholder) {
+			return fmt.Errorf("%s is a placeholder; generate a random secret", name)
+		}
+	}
+	if len(secret) < MinSecretLength {
+		return fmt.Errorf("%s must be at least %d bytes", name, MinSecretLength)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package config
+
+import "testing"
+
+func TestCheckSecret(t *testing.T) {
+	for _, tc := range []struct {
+		secret string
+		ok     bool
+	}{
+		{"", false},
+		{"change_me_to_a_long_random_secret", false},
+		{"CHANGEME-CHANGEME-CHANGEME-CHANGEME", false},
+		{"0123456789abcdef", false},
+		{"0123456789abcdef0123456789abcdef", true},
+	} {
+		if err := CheckSecret("auth.jwt.secret", tc.secret); (err == nil) != tc.ok {
+			t.Errorf("CheckSecret(%q) = %v, want ok %v", tc.secret, err, tc.ok)
+		}
+	}
+}
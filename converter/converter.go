@@ -0,0 +1,65 @@
+package converter
+
+import (
+	"context"
+	"encoding/xml"
+	"strings"
+
+	"github.com/IlhamSetiaji/report-converter/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Renderer fills the placeholders of a template file. It reads the template
+// from templatePath, writes the result into workDir and returns its path.
+type Renderer interface {
+	Render(ctx context.Context, workDir string, templatePath string, data map[string]string) (string, error)
+}
+
+var renderers = map[string]Renderer{
+	"docx": &docxRenderer{},
+	"xlsx": &xlsxRenderer{},
+}
+
+// Lookup returns the renderer registered under name, as referenced by
+// entity.TemplateTypeSpec.Renderer.
+func Lookup(name string) (Renderer, bool) {
+	r, ok := renderers[name]
+	if !ok {
+		return nil, false
+	}
+	return &tracedRenderer{name: name, next: r}, true
+}
+
+// tracedRenderer wraps every render in a span named after the renderer.
+type tracedRenderer struct {
+	name string
+	next Renderer
+}
+
+func (r *tracedRenderer) Render(ctx context.Context, workDir string, templatePath string, data map[string]string) (string, error) {
+	ctx, span := tracing.Start(ctx, r.name+".render", trace.WithAttributes(attribute.Int("converter.fields", len(data))))
+	defer span.End()
+
+	path, err := r.next.Render(ctx, workDir, templatePath, data)
+	if err != nil {
+		tracing.Fail(span, err)
+	}
+	return path, err
+}
+
+// escapeXML escapes a value for insertion into WordprocessingML or
+// SpreadsheetML text, so data cannot break or inject markup.
+func escapeXML(value string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(value))
+	return sb.String()
+}
+
+// replacePlaceholders substitutes every {{.key}} in content.
+func replacePlaceholders(content string, data map[string]string) string {
+	for key, value := range data {
+		content = strings.ReplaceAll(content, "{{."+key+"}}", escapeXML(value))
+	}
+	return content
+}
@@ -0,0 +1,32 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/nguyenthenguyen/docx"
+)
+
+type docxRenderer struct{}
+
+func (r *docxRenderer) Render(ctx context.Context, workDir string, templatePath string, data map[string]string) (string, error) {
+	// Read the docx file
+	doc, err := docx.ReadDocxFile(templatePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read document: %v", err)
+	}
+	defer doc.Close()
+
+	docxContent := doc.Editable()
+
+	// Replace all variables in the content
+	docxContent.SetContent(replacePlaceholders(docxContent.GetContent(), data))
+
+	// Save the modified DOCX
+	modifiedDocxPath := filepath.Join(workDir, "modified_"+filepath.Base(templatePath))
+	if err := docxContent.WriteToFile(modifiedDocxPath); err != nil {
+		return "", fmt.Errorf("failed to save modified document: %v", err)
+	}
+	return modifiedDocxPath, nil
+}
@@ -0,0 +1,60 @@
+package converter
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+var docxPages = regexp.MustCompile(`<Pages>(\d+)</Pages>`)
+
+// CountPages returns the number of pages of a rendered document, or 0 when
+// the format has no notion of pages it can cheaply tell. For PDF it is the
+// /Count of the page tree root; for DOCX the count Word stored in
+// docProps/app.xml, which may be stale.
+func CountPages(path string, format string) (int, error) {
+	switch format {
+	case "pdf":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return 0, err
+		}
+		return pdfPageCount(data)
+	case "docx":
+		return docxPageCount(path)
+	default:
+		return 0, nil
+	}
+}
+
+func docxPageCount(path string) (int, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if file.Name != "docProps/app.xml" {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return 0, err
+		}
+		defer rc.Close()
+
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, io.LimitReader(rc, 1<<20)); err != nil {
+			return 0, err
+		}
+		if match := docxPages.FindSubmatch(buf.Bytes()); match != nil {
+			return strconv.Atoi(string(match[1]))
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,241 @@
+package converter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// buildPDF lays out objects, numbered from 1, with a classic xref table and
+// a trailer naming object 1 as the catalog.
+func buildPDF(objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestPDFPageCountReadsPageTreeRoot(t *testing.T) {
+	// Two pages under an intermediate node and one directly under the root.
+	// The content stream mentions /Type /Page as text, which a scan for page
+	// objects would count.
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R /Outlines << /Count 9 >> >>",
+		"<< /Type /Pages /Kids [3 0 R 6 0 R] /Count 3 >>",
+		"<< /Type /Pages /Parent 2 0 R /Kids [4 0 R 5 0 R] /Count 2 >>",
+		"<< /Type /Page /Parent 3 0 R /Contents 7 0 R >>",
+		"<< /Type/Page /Parent 3 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 (/Type /Page) >> >> >>",
+		"<< /Length 44 >>\nstream\nBT (/Type /Page /Type /Page /Type /Page) Tj ET\nendstream",
+	)
+
+	count, err := pdfPageCount(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("page count = %d, want 3", count)
+	}
+}
+
+// incrementalPDF is a one-page PDF with an incremental update that adds a
+// second page. The update's xref table only lists the changed objects.
+func incrementalPDF() []byte {
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R >>",
+	)
+
+	var buf bytes.Buffer
+	buf.Write(data)
+	root := buf.Len()
+	buf.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >>\nendobj\n")
+	page := buf.Len()
+	buf.WriteString("4 0 obj\n<< /Type /Page /Parent 2 0 R >>\nendobj\n")
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n2 1\n%010d 00000 n \n4 1\n%010d 00000 n \n", root, page)
+	fmt.Fprintf(&buf, "trailer\n<< /Size 5 /Root 1 0 R /Prev %d >>\nstartxref\n%d\n%%%%EOF\n",
+		bytes.LastIndex(data, []byte("\nxref\n"))+1, xref)
+	return buf.Bytes()
+}
+
+func TestPDFPageCountFollowsIncrementalUpdates(t *testing.T) {
+	count, err := pdfPageCount(incrementalPDF())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("page count = %d, want 2", count)
+	}
+}
+
+func TestPDFPageCountUsesXrefOffsets(t *testing.T) {
+	// A stream quoting an object header after the real object must not
+	// shadow it, since the xref table says where the object is.
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Length 36 >>\nstream\n2 0 obj << /Type /Pages /Count 7 >>\nendstream",
+	)
+
+	count, err := pdfPageCount(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("page count = %d, want 1", count)
+	}
+}
+
+// objectStreamPDF is a PDF 1.5 file keeping the catalog and page tree in a
+// compressed object stream and naming the root in a cross-reference stream.
+func objectStreamPDF() []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >>",
+	}
+	var header, body strings.Builder
+	for i, object := range objects {
+		fmt.Fprintf(&header, "%d %d ", i+1, body.Len())
+		body.WriteString(object + "\n")
+	}
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write([]byte(header.String() + body.String()))
+	zw.Close()
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.5\n")
+	fmt.Fprintf(&buf, "5 0 obj\n<< /Type /ObjStm /N 2 /First %d /Filter /FlateDecode /Length %d >>\nstream\n", header.Len(), compressed.Len())
+	buf.Write(compressed.Bytes())
+	buf.WriteString("\nendstream\nendobj\n")
+	buf.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R >>\nendobj\n")
+	buf.WriteString("4 0 obj\n<< /Type /Page /Parent 2 0 R >>\nendobj\n")
+	xref := buf.Len()
+	buf.WriteString("6 0 obj\n<< /Type /XRef /Size 7 /Root 1 0 R /W [1 2 1] /Length 0 >>\nstream\n\nendstream\nendobj\n")
+	fmt.Fprintf(&buf, "startxref\n%d\n%%%%EOF\n", xref)
+	return buf.Bytes()
+}
+
+func TestPDFPageCountReadsObjectStreams(t *testing.T) {
+	count, err := pdfPageCount(objectStreamPDF())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("page count = %d, want 2", count)
+	}
+}
+
+// malformedPDFs are files pdfPageCount must reject.
+func malformedPDFs() map[string][]byte {
+	cyclic := buildPDF("<< /Type /Catalog /Pages 9 0 R >>")
+	cyclic = bytes.Replace(cyclic, []byte("/Root 1 0 R"), []byte(fmt.Sprintf("/Root 1 0 R /Prev %d", bytes.LastIndex(cyclic, []byte("\nxref\n"))+1)), 1)
+	return map[string][]byte{
+		"empty":          nil,
+		"no trailer":     []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n"),
+		"missing root":   buildPDF("<< /Type /Catalog >>"),
+		"missing object": buildPDF("<< /Type /Catalog /Pages 9 0 R >>"),
+		"no count":       buildPDF("<< /Type /Catalog /Pages 2 0 R >>", "<< /Type /Pages /Kids [] >>"),
+		"cyclic prev":    cyclic,
+		"deep nesting":   buildPDF("<< /Type /Catalog /Pages 2 0 R /A " + strings.Repeat("[", 100000) + " >>"),
+	}
+}
+
+func TestPDFPageCountRejectsMalformedFiles(t *testing.T) {
+	for name, data := range malformedPDFs() {
+		if _, err := pdfPageCount(data); !errors.Is(err, errMalformedPDF) {
+			t.Errorf("%s: err = %v, want errMalformedPDF", name, err)
+		}
+	}
+}
+
+func FuzzPDFPageCount(f *testing.F) {
+	f.Add(buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R >>",
+	))
+	f.Add(incrementalPDF())
+	f.Add(objectStreamPDF())
+	for _, data := range malformedPDFs() {
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		count, err := pdfPageCount(data)
+		if err == nil && count < 0 {
+			t.Errorf("page count = %d", count)
+		}
+		if err != nil && !errors.Is(err, errMalformedPDF) {
+			t.Errorf("err = %v, want errMalformedPDF", err)
+		}
+	})
+}
+
+// TestCountPagesOfConvertedDocument counts the pages of a PDF LibreOffice
+// rendered. It needs LibreOffice and checks against pdfinfo when present.
+func TestCountPagesOfConvertedDocument(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "lines.txt")
+	var text strings.Builder
+	for i := 1; i <= 200; i++ {
+		fmt.Fprintf(&text, "line %d\n", i)
+	}
+	if err := os.WriteFile(input, []byte(text.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	conversion, err := ConvertToPDF(context.Background(), input, dir)
+	if errors.Is(err, errNoLibreOffice) {
+		t.Skip("LibreOffice is not installed")
+	}
+	if err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+
+	count, err := CountPages(conversion.Path, "pdf")
+	if err != nil {
+		t.Fatalf("count pages: %v", err)
+	}
+	if count < 2 {
+		t.Errorf("200 lines rendered to %d pages", count)
+	}
+
+	pdfinfo, err := exec.LookPath("pdfinfo")
+	if err != nil {
+		return
+	}
+	out, err := exec.Command(pdfinfo, conversion.Path).Output()
+	if err != nil {
+		t.Fatalf("pdfinfo: %v", err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if value, ok := strings.CutPrefix(line, "Pages:"); ok {
+			if want, _ := strconv.Atoi(strings.TrimSpace(value)); count != want {
+				t.Errorf("page count = %d, pdfinfo reports %d", count, want)
+			}
+			return
+		}
+	}
+	t.Error("pdfinfo reported no page count")
+}
@@ -0,0 +1,514 @@
+package converter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+const (
+	// maxObjectStreamData bounds how much object stream data is inflated in
+	// total while indexing a file.
+	maxObjectStreamData = 64 << 20
+	// maxXrefSections bounds how many cross-reference tables are followed
+	// through /Prev, which also ends a /Prev cycle.
+	maxXrefSections = 256
+	// maxPDFNesting bounds how deeply arrays and dictionaries may nest.
+	maxPDFNesting = 64
+	// maxObjectStreams and maxObjectStreamDict bound how many object streams
+	// are read and the size of their dictionaries, which are short. Together
+	// they keep indexing linear however the body is laid out.
+	maxObjectStreams    = 4096
+	maxObjectStreamDict = 1 << 10
+)
+
+var (
+	errMalformedPDF = errors.New("malformed PDF")
+
+	pdfReference = regexp.MustCompile(`^(\d+)\s+(\d+)\s+R$`)
+)
+
+// pdfRef identifies an indirect object.
+type pdfRef struct {
+	num int
+	gen int
+}
+
+// pdfDict is the top level of a PDF dictionary. Values are kept as their
+// source text and only interpreted on request.
+type pdfDict map[string][]byte
+
+func (d pdfDict) ref(key string) (pdfRef, bool) {
+	match := pdfReference.FindSubmatch(d[key])
+	if match == nil {
+		return pdfRef{}, false
+	}
+	num, _ := strconv.Atoi(string(match[1]))
+	gen, _ := strconv.Atoi(string(match[2]))
+	return pdfRef{num: num, gen: gen}, true
+}
+
+func (d pdfDict) int(key string) (int, bool) {
+	n, err := strconv.Atoi(string(d[key]))
+	return n, err == nil
+}
+
+// pdfPageCount reads the page count from the root of the page tree: the
+// trailer names the document catalog, and the catalog names the root node,
+// whose /Count covers every page below it.
+func pdfPageCount(data []byte) (int, error) {
+	file, err := openPDF(data)
+	if err != nil {
+		return 0, err
+	}
+	root, ok := file.trailer.ref("Root")
+	if !ok {
+		return 0, fmt.Errorf("%w: trailer has no /Root", errMalformedPDF)
+	}
+	catalog, err := file.object(root)
+	if err != nil {
+		return 0, err
+	}
+	pagesRef, ok := catalog.ref("Pages")
+	if !ok {
+		return 0, fmt.Errorf("%w: catalog has no /Pages", errMalformedPDF)
+	}
+	pages, err := file.object(pagesRef)
+	if err != nil {
+		return 0, err
+	}
+	count, ok := pages.int("Count")
+	if !ok || count < 0 {
+		return 0, fmt.Errorf("%w: page tree has no /Count", errMalformedPDF)
+	}
+	return count, nil
+}
+
+// pdfFile finds the objects of a PDF. Objects are located through the
+// file's classic cross-reference tables. Cross-reference streams are not
+// decoded, so objects they list, like any the tables miss, are found in an
+// index of the file body and its object streams, built once when first
+// needed.
+type pdfFile struct {
+	data    []byte
+	trailer pdfDict
+	xref    map[pdfRef]int
+	body    map[pdfRef]int
+	streams map[int][]byte
+}
+
+// openPDF reads the newest trailer, the one after the xref table the last
+// startxref points at or the dictionary of the cross-reference stream it
+// points at, and the offsets in the xref tables reachable from it.
+func openPDF(data []byte) (*pdfFile, error) {
+	at := bytes.LastIndex(data, []byte("startxref"))
+	if at < 0 {
+		return nil, fmt.Errorf("%w: no startxref", errMalformedPDF)
+	}
+	offset, _, ok := readPDFInt(data, at+len("startxref"))
+	if !ok || offset < 0 || offset >= len(data) {
+		return nil, fmt.Errorf("%w: startxref points outside the file", errMalformedPDF)
+	}
+
+	file := &pdfFile{data: data, xref: map[pdfRef]int{}}
+	if !bytes.HasPrefix(data[skipPDFSpace(data, offset):], []byte("xref")) {
+		_, end, ok := readPDFObjHeader(data, offset)
+		if !ok {
+			return nil, fmt.Errorf("%w: startxref points at neither xref table nor stream", errMalformedPDF)
+		}
+		trailer, _, err := parsePDFDict(data, end, 0)
+		if err != nil {
+			return nil, err
+		}
+		file.trailer = trailer
+		return file, nil
+	}
+
+	// Tables are read newest first, so offsets already known take
+	// precedence over those of earlier revisions.
+	for sections := 0; ; sections++ {
+		if sections == maxXrefSections {
+			return nil, fmt.Errorf("%w: too many xref sections", errMalformedPDF)
+		}
+		trailer, err := file.readXrefTable(offset)
+		if err != nil {
+			return nil, err
+		}
+		if file.trailer == nil {
+			file.trailer = trailer
+		}
+		prev, ok := trailer.int("Prev")
+		if !ok || prev < 0 || prev >= len(data) || !bytes.HasPrefix(data[skipPDFSpace(data, prev):], []byte("xref")) {
+			return file, nil
+		}
+		offset = prev
+	}
+}
+
+// readXrefTable records the in-use entries of the xref table at offset
+// that are not known yet and returns the trailer following it.
+func (f *pdfFile) readXrefTable(offset int) (pdfDict, error) {
+	pos := skipPDFSpace(f.data, offset) + len("xref")
+	for {
+		pos = skipPDFSpace(f.data, pos)
+		if bytes.HasPrefix(f.data[pos:], []byte("trailer")) {
+			trailer, _, err := parsePDFDict(f.data, pos+len("trailer"), 0)
+			return trailer, err
+		}
+
+		first, end, okFirst := readPDFInt(f.data, pos)
+		count, end, okCount := readPDFInt(f.data, end)
+		if !okFirst || !okCount || first < 0 || count < 0 {
+			return nil, fmt.Errorf("%w: bad xref subsection at offset %d", errMalformedPDF, pos)
+		}
+		pos = end
+		for i := 0; i < count; i++ {
+			entryOffset, end, okOffset := readPDFInt(f.data, pos)
+			gen, end, okGen := readPDFInt(f.data, end)
+			end = skipPDFSpace(f.data, end)
+			if !okOffset || !okGen || end >= len(f.data) || (f.data[end] != 'n' && f.data[end] != 'f') {
+				return nil, fmt.Errorf("%w: bad xref entry at offset %d", errMalformedPDF, pos)
+			}
+			ref := pdfRef{num: first + i, gen: gen}
+			if _, known := f.xref[ref]; !known && f.data[end] == 'n' {
+				f.xref[ref] = entryOffset
+			}
+			pos = end + 1
+		}
+	}
+}
+
+// object returns the dictionary of an indirect object. An object missing
+// from the xref tables is taken from its last definition in the file body,
+// as in incremental updates, or else from an object stream.
+func (f *pdfFile) object(ref pdfRef) (pdfDict, error) {
+	if offset, ok := f.xref[ref]; ok {
+		if found, end, ok := readPDFObjHeader(f.data, offset); ok && found == ref {
+			dict, _, err := parsePDFDict(f.data, end, 0)
+			return dict, err
+		}
+	}
+
+	if f.body == nil {
+		f.body = indexPDFBody(f.data)
+	}
+	if end, ok := f.body[ref]; ok {
+		dict, _, err := parsePDFDict(f.data, end, 0)
+		return dict, err
+	}
+
+	if ref.gen == 0 {
+		if f.streams == nil {
+			f.streams = indexPDFObjectStreams(f.data, f.body)
+		}
+		if object, ok := f.streams[ref.num]; ok {
+			dict, _, err := parsePDFDict(object, 0, 0)
+			return dict, err
+		}
+	}
+	return nil, fmt.Errorf("%w: object %d %d not found", errMalformedPDF, ref.num, ref.gen)
+}
+
+// indexPDFBody finds every "num gen obj" header in data in one pass and maps
+// each object to the position after its last header.
+func indexPDFBody(data []byte) map[pdfRef]int {
+	index := map[pdfRef]int{}
+	for pos := 0; ; {
+		at := bytes.Index(data[pos:], []byte("obj"))
+		if at < 0 {
+			return index
+		}
+		at += pos
+		pos = at + len("obj")
+		if pos < len(data) && !isPDFSpace(data[pos]) && !isPDFDelimiter(data[pos]) {
+			continue
+		}
+
+		// Read the generation and object number backwards.
+		genEnd := skipPDFSpaceBack(data, at)
+		genStart := skipPDFDigitsBack(data, genEnd)
+		numEnd := skipPDFSpaceBack(data, genStart)
+		numStart := skipPDFDigitsBack(data, numEnd)
+		if genEnd == at || genStart == genEnd || numEnd == genStart || numStart == numEnd {
+			continue
+		}
+		if numStart > 0 && !isPDFSpace(data[numStart-1]) && !isPDFDelimiter(data[numStart-1]) {
+			continue
+		}
+		num, errNum := strconv.Atoi(string(data[numStart:numEnd]))
+		gen, errGen := strconv.Atoi(string(data[genStart:genEnd]))
+		if errNum == nil && errGen == nil {
+			index[pdfRef{num: num, gen: gen}] = pos
+		}
+	}
+}
+
+// indexPDFObjectStreams maps the numbers of the objects held in the
+// Flate-compressed object streams of the body to their source text. Streams
+// that cannot be read are skipped; their objects are then not found.
+func indexPDFObjectStreams(data []byte, body map[pdfRef]int) map[int][]byte {
+	// Visit streams in file order so later revisions win.
+	starts := make([]int, 0, len(body))
+	for _, start := range body {
+		starts = append(starts, start)
+	}
+	sort.Ints(starts)
+
+	index := map[int][]byte{}
+	budget := int64(maxObjectStreamData)
+	streams := 0
+	for _, start := range starts {
+		if budget <= 0 || streams == maxObjectStreams {
+			break
+		}
+		head := data[:min(len(data), start+maxObjectStreamDict)]
+		if !bytes.Contains(head[start:], []byte("/ObjStm")) {
+			continue
+		}
+		streams++
+		dict, end, err := parsePDFDict(head, start, 0)
+		if err != nil || string(dict["Type"]) != "/ObjStm" {
+			continue
+		}
+		if filter := string(dict["Filter"]); filter != "/FlateDecode" && filter != "[/FlateDecode]" {
+			continue
+		}
+		n, okN := dict.int("N")
+		first, okFirst := dict.int("First")
+		if !okN || !okFirst || first < 0 {
+			continue
+		}
+
+		pos := skipPDFSpace(data, end)
+		if !bytes.HasPrefix(data[pos:], []byte("stream")) {
+			continue
+		}
+		pos += len("stream")
+		if bytes.HasPrefix(data[pos:], []byte("\r")) {
+			pos++
+		}
+		if bytes.HasPrefix(data[pos:], []byte("\n")) {
+			pos++
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(data[pos:]))
+		if err != nil {
+			continue
+		}
+		content, err := io.ReadAll(io.LimitReader(zr, budget))
+		zr.Close()
+		budget -= int64(len(content))
+		if err != nil || first > len(content) {
+			continue
+		}
+
+		// The stream starts with N pairs of object number and offset.
+		fields := bytes.Fields(content[:first])
+		for i := 0; i+1 < len(fields) && i < 2*n; i += 2 {
+			num, errNum := strconv.Atoi(string(fields[i]))
+			offset, errOffset := strconv.Atoi(string(fields[i+1]))
+			if errNum == nil && errOffset == nil && offset >= 0 && first+offset < len(content) {
+				index[num] = content[first+offset:]
+			}
+		}
+	}
+	return index
+}
+
+// readPDFObjHeader reads a "num gen obj" header at or after pos and returns
+// the object it names and the position following it.
+func readPDFObjHeader(data []byte, pos int) (pdfRef, int, bool) {
+	if pos < 0 || pos >= len(data) {
+		return pdfRef{}, 0, false
+	}
+	num, end, okNum := readPDFInt(data, pos)
+	gen, end, okGen := readPDFInt(data, end)
+	end = skipPDFSpace(data, end)
+	if !okNum || !okGen || skipPDFToken(data, end) != end+len("obj") || !bytes.HasPrefix(data[end:], []byte("obj")) {
+		return pdfRef{}, 0, false
+	}
+	return pdfRef{num: num, gen: gen}, end + len("obj"), true
+}
+
+// readPDFInt reads the integer at or after pos and returns it with the
+// position following it.
+func readPDFInt(data []byte, pos int) (int, int, bool) {
+	pos = skipPDFSpace(data, pos)
+	end := skipPDFToken(data, pos)
+	n, err := strconv.Atoi(string(data[pos:end]))
+	return n, end, err == nil
+}
+
+// parsePDFDict parses the dictionary starting at or after pos and returns
+// it with the position following it. depth is the nesting level of the
+// dictionary.
+func parsePDFDict(data []byte, pos int, depth int) (pdfDict, int, error) {
+	if depth > maxPDFNesting {
+		return nil, 0, fmt.Errorf("%w: objects nested too deeply", errMalformedPDF)
+	}
+	pos = skipPDFSpace(data, pos)
+	if !bytes.HasPrefix(data[pos:], []byte("<<")) {
+		return nil, 0, fmt.Errorf("%w: expected a dictionary at offset %d", errMalformedPDF, pos)
+	}
+	pos += 2
+
+	dict := pdfDict{}
+	for {
+		pos = skipPDFSpace(data, pos)
+		switch {
+		case pos >= len(data):
+			return nil, 0, fmt.Errorf("%w: unterminated dictionary", errMalformedPDF)
+		case bytes.HasPrefix(data[pos:], []byte(">>")):
+			return dict, pos + 2, nil
+		case data[pos] != '/':
+			return nil, 0, fmt.Errorf("%w: expected a name at offset %d", errMalformedPDF, pos)
+		}
+
+		keyEnd := skipPDFToken(data, pos+1)
+		key := string(data[pos+1 : keyEnd])
+		valueStart := skipPDFSpace(data, keyEnd)
+		valueEnd, err := skipPDFObject(data, valueStart, depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		// An indirect reference is three tokens: number, generation, R.
+		if _, err := strconv.Atoi(string(data[valueStart:valueEnd])); err == nil {
+			genStart := skipPDFSpace(data, valueEnd)
+			genEnd := skipPDFToken(data, genStart)
+			rStart := skipPDFSpace(data, genEnd)
+			if _, err := strconv.Atoi(string(data[genStart:genEnd])); err == nil && skipPDFToken(data, rStart) == rStart+1 && data[rStart] == 'R' {
+				valueEnd = rStart + 1
+			}
+		}
+		dict[key] = data[valueStart:valueEnd]
+		pos = valueEnd
+	}
+}
+
+// skipPDFObject returns the position after the direct object at pos, which
+// is nested depth levels deep.
+func skipPDFObject(data []byte, pos int, depth int) (int, error) {
+	if depth > maxPDFNesting {
+		return 0, fmt.Errorf("%w: objects nested too deeply", errMalformedPDF)
+	}
+	if pos >= len(data) {
+		return 0, fmt.Errorf("%w: unexpected end of file", errMalformedPDF)
+	}
+	switch data[pos] {
+	case '<':
+		if bytes.HasPrefix(data[pos:], []byte("<<")) {
+			_, end, err := parsePDFDict(data, pos, depth)
+			return end, err
+		}
+		end := bytes.IndexByte(data[pos:], '>')
+		if end < 0 {
+			return 0, fmt.Errorf("%w: unterminated hex string", errMalformedPDF)
+		}
+		return pos + end + 1, nil
+	case '(':
+		depth := 0
+		for i := pos; i < len(data); i++ {
+			switch data[i] {
+			case '\\':
+				i++
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth == 0 {
+					return i + 1, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("%w: unterminated string", errMalformedPDF)
+	case '[':
+		pos++
+		for {
+			pos = skipPDFSpace(data, pos)
+			if pos >= len(data) {
+				return 0, fmt.Errorf("%w: unterminated array", errMalformedPDF)
+			}
+			if data[pos] == ']' {
+				return pos + 1, nil
+			}
+			end, err := skipPDFObject(data, pos, depth+1)
+			if err != nil {
+				return 0, err
+			}
+			pos = end
+		}
+	case '/':
+		return skipPDFToken(data, pos+1), nil
+	default:
+		end := skipPDFToken(data, pos)
+		if end == pos {
+			return 0, fmt.Errorf("%w: unexpected %q at offset %d", errMalformedPDF, data[pos], pos)
+		}
+		return end, nil
+	}
+}
+
+// skipPDFToken returns the position of the first whitespace or delimiter at
+// or after pos.
+func skipPDFToken(data []byte, pos int) int {
+	for pos < len(data) && !isPDFSpace(data[pos]) && !isPDFDelimiter(data[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// skipPDFSpace returns the position of the first byte at or after pos that
+// is neither whitespace nor part of a comment.
+func skipPDFSpace(data []byte, pos int) int {
+	for pos < len(data) {
+		switch {
+		case isPDFSpace(data[pos]):
+			pos++
+		case data[pos] == '%':
+			for pos < len(data) && data[pos] != '\n' && data[pos] != '\r' {
+				pos++
+			}
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+// skipPDFSpaceBack returns the position after the last non-whitespace byte
+// before pos.
+func skipPDFSpaceBack(data []byte, pos int) int {
+	for pos > 0 && isPDFSpace(data[pos-1]) {
+		pos--
+	}
+	return pos
+}
+
+// skipPDFDigitsBack returns the position of the first of the digits
+// immediately before pos.
+func skipPDFDigitsBack(data []byte, pos int) int {
+	for pos > 0 && data[pos-1] >= '0' && data[pos-1] <= '9' {
+		pos--
+	}
+	return pos
+}
+
+func isPDFSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/metrics"
+	"github.com/IlhamSetiaji/report-converter/tracing"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const engineLibreOffice = "libreoffice"
+
+var errNoLibreOffice = errors.New("LibreOffice not found in standard locations")
+
+var (
+	// slots bounds how many LibreOffice processes run at once; further
+	// conversions wait in line for a free slot.
+	slots             = make(chan struct{}, runtime.NumCPU())
+	conversionTimeout = 30 * time.Second
+)
+
+// Configure applies the converter section of the configuration. It must be
+// called before the first conversion.
+func Configure(conf *config.Config) {
+	if conf.Converter == nil {
+		return
+	}
+	if conf.Converter.MaxConcurrent > 0 {
+		slots = make(chan struct{}, conf.Converter.MaxConcurrent)
+	}
+	if conf.Converter.Timeout > 0 {
+		conversionTimeout = conf.Converter.Timeout
+	}
+}
+
+// Conversion is the outcome of a LibreOffice conversion.
+type Conversion struct {
+	Path string
+	// CPUTime is the user and system time LibreOffice spent, including
+	// the helper processes it waited for.
+	CPUTime time.Duration
+}
+
+// ConvertToPDF converts a document to PDF with headless LibreOffice and
+// returns the PDF written to outputDir. It waits for a free conversion slot
+// first, giving up when ctx is done.
+func ConvertToPDF(ctx context.Context, inputPath, outputDir string) (*Conversion, error) {
+	ctx, span := tracing.Start(ctx, "soffice.convert", trace.WithAttributes(attribute.String("converter.input", filepath.Base(inputPath))))
+	defer span.End()
+
+	conversion, err := convertToPDF(ctx, inputPath, outputDir)
+	if err != nil {
+		tracing.Fail(span, err)
+	}
+	return conversion, err
+}
+
+func convertToPDF(ctx context.Context, inputPath, outputDir string) (*Conversion, error) {
+	// Try both direct command and container-specific paths
+	loPaths := []string{
+		"/usr/bin/soffice", // Linux default
+		"/usr/local/bin/soffice",
+		"/opt/libreoffice/program/soffice",
+	}
+
+	if runtime.GOOS == "windows" {
+		loPaths = append(loPaths, []string{
+			`C:\Program Files\LibreOffice\program\soffice.exe`,
+			`C:\Program Files (x86)\LibreOffice\program\soffice.exe`,
+		}...)
+	}
+
+	var loPath string
+	for _, path := range loPaths {
+		if _, err := os.Stat(path); err == nil {
+			loPath = path
+			break
+		}
+	}
+
+	if loPath == "" {
+		metrics.ConversionFailures.WithLabelValues(engineLibreOffice, "unavailable").Inc()
+		return nil, errNoLibreOffice
+	}
+
+	metrics.ConversionQueueDepth.Inc()
+	select {
+	case slots <- struct{}{}:
+		metrics.ConversionQueueDepth.Dec()
+	case <-ctx.Done():
+		metrics.ConversionQueueDepth.Dec()
+		return nil, fmt.Errorf("waiting for a conversion slot: %w", ctx.Err())
+	}
+	trace.SpanFromContext(ctx).AddEvent("conversion slot acquired")
+	metrics.ActiveConversions.Inc()
+	defer func() {
+		metrics.ActiveConversions.Dec()
+		<-slots
+	}()
+	started := time.Now()
+
+	// Every run gets a user profile of its own: concurrent instances sharing
+	// one lock each other out and fail or write nothing.
+	profileDir, err := os.MkdirTemp("", "soffice-profile-*")
+	if err != nil {
+		return nil, fmt.Errorf("create LibreOffice profile: %w", err)
+	}
+	defer os.RemoveAll(profileDir)
+
+	ctx, cancel := context.WithTimeout(ctx, conversionTimeout)
+	defer cancel()
+
+	// Add container-specific environment variables
+	env := os.Environ()
+	env = append(env, "HOME=/tmp") // LibreOffice needs a home directory
+
+	cmd := exec.CommandContext(ctx,
+		loPath,
+		"-env:UserInstallation="+fileURL(profileDir),
+		"--headless",
+		"--convert-to", "pdf",
+		"--outdir", outputDir,
+		inputPath,
+	)
+	cmd.Env = env
+	// Helpers that inherited the output pipes must not keep Wait from
+	// returning once soffice itself was killed.
+	cmd.WaitDelay = 5 * time.Second
+
+	log := logger.FromContext(ctx).WithFields(logrus.Fields{
+		"soffice": loPath,
+		"input":   inputPath,
+		"outdir":  outputDir,
+	})
+	log.Debug("Running LibreOffice")
+
+	output, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		metrics.ConversionDuration.WithLabelValues(engineLibreOffice, "timeout").Observe(time.Since(started).Seconds())
+		metrics.ConversionFailures.WithLabelValues(engineLibreOffice, "timeout").Inc()
+		log.WithField("timeout", conversionTimeout.String()).Error("LibreOffice conversion timed out")
+		return nil, fmt.Errorf("PDF conversion timed out after %s", conversionTimeout)
+	}
+	if err != nil {
+		log.WithError(err).WithField("output", string(output)).Error("LibreOffice conversion failed")
+		metrics.ConversionDuration.WithLabelValues(engineLibreOffice, "error").Observe(time.Since(started).Seconds())
+		metrics.ConversionFailures.WithLabelValues(engineLibreOffice, "error").Inc()
+		return nil, fmt.Errorf("PDF conversion failed: %v, output: %s", err, string(output))
+	}
+	metrics.ConversionDuration.WithLabelValues(engineLibreOffice, "success").Observe(time.Since(started).Seconds())
+
+	// Construct the expected PDF file path
+	pdfFileName := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath)) + ".pdf"
+	pdfPath := filepath.Join(outputDir, pdfFileName)
+
+	// Verify the PDF was created
+	if _, err := os.Stat(pdfPath); os.IsNotExist(err) {
+		metrics.ConversionFailures.WithLabelValues(engineLibreOffice, "error").Inc()
+		return nil, fmt.Errorf("PDF file was not created")
+	}
+
+	return &Conversion{
+		Path:    pdfPath,
+		CPUTime: cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime(),
+	}, nil
+}
+
+// fileURL turns an absolute path into the file URL LibreOffice expects.
+func fileURL(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
@@ -0,0 +1,14 @@
+package converter
+
+import "testing"
+
+func TestFileURL(t *testing.T) {
+	for path, want := range map[string]string{
+		"/tmp/soffice-profile-1":  "file:///tmp/soffice-profile-1",
+		"/tmp/with space/profile": "file:///tmp/with%20space/profile",
+	} {
+		if got := fileURL(path); got != want {
+			t.Errorf("fileURL(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
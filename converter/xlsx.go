@@ -0,0 +1,77 @@
+package converter
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/IlhamSetiaji/report-converter/utils"
+)
+
+type xlsxRenderer struct{}
+
+// maxWorkbookSize bounds the bytes decompressed from a workbook while it is
+// rendered.
+var maxWorkbookSize int64 = 100 << 20
+
+// Render replaces placeholders in the shared string table and in inline
+// strings of every worksheet. All other parts are copied unchanged.
+func (r *xlsxRenderer) Render(ctx context.Context, workDir string, templatePath string, data map[string]string) (string, error) {
+	src, err := zip.OpenReader(templatePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read workbook: %v", err)
+	}
+	defer src.Close()
+
+	modifiedPath := filepath.Join(workDir, "modified_"+filepath.Base(templatePath))
+	out, err := os.Create(modifiedPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create workbook: %v", err)
+	}
+	defer out.Close()
+
+	dst := zip.NewWriter(out)
+	remaining := maxWorkbookSize
+	for _, f := range src.File {
+		n, err := copyXlsxPart(dst, f, data, remaining)
+		if err != nil {
+			return "", fmt.Errorf("failed to write %s: %v", f.Name, err)
+		}
+		remaining -= n
+	}
+	if err := dst.Close(); err != nil {
+		return "", fmt.Errorf("failed to save modified workbook: %v", err)
+	}
+	return modifiedPath, nil
+}
+
+// copyXlsxPart writes a part to dst, reading at most budget decompressed
+// bytes, and returns how many it read.
+func copyXlsxPart(dst *zip.Writer, f *zip.File, data map[string]string, budget int64) (int64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	w, err := dst.CreateHeader(&zip.FileHeader{Name: f.Name, Method: f.Method, Modified: f.Modified})
+	if err != nil {
+		return 0, err
+	}
+
+	limited := utils.LimitReader(rc, budget)
+	if f.Name != "xl/sharedStrings.xml" && !strings.HasPrefix(f.Name, "xl/worksheets/sheet") {
+		return io.Copy(w, limited)
+	}
+
+	content, err := io.ReadAll(limited)
+	if err != nil {
+		return 0, err
+	}
+	_, err = io.WriteString(w, replacePlaceholders(string(content), data))
+	return int64(len(content)), err
+}
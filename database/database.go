@@ -4,4 +4,6 @@ import "gorm.io/gorm"
 
 type Database interface {
 	GetDb() *gorm.DB
+	// Close closes the connection pool.
+	Close() error
 }
@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/IlhamSetiaji/report-converter/tracing"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -35,6 +36,9 @@ func NewPostgresDatabase(conf *config.Config) Database {
 		if err != nil {
 			panic("failed to connect database")
 		}
+		if err := db.Use(tracing.GormPlugin{}); err != nil {
+			panic(err)
+		}
 
 		dbInstance = &postgresDatabase{Db: db}
 	})
@@ -45,3 +49,11 @@ func NewPostgresDatabase(conf *config.Config) Database {
 func (p *postgresDatabase) GetDb() *gorm.DB {
 	return dbInstance.Db
 }
+
+func (p *postgresDatabase) Close() error {
+	sqlDB, err := dbInstance.Db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -0,0 +1,44 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/entity"
+	"github.com/IlhamSetiaji/report-converter/response"
+)
+
+type IApiKeyDTO interface {
+	ConvertEntityToResponse(ent *entity.ApiKey) *response.ApiKeyResponse
+}
+
+type ApiKeyDTO struct{}
+
+func NewApiKeyDTO() IApiKeyDTO {
+	return &ApiKeyDTO{}
+}
+
+func (a *ApiKeyDTO) ConvertEntityToResponse(ent *entity.ApiKey) *response.ApiKeyResponse {
+	scopes := []string(ent.Scopes)
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	return &response.ApiKeyResponse{
+		ID:         ent.ID.String(),
+		Name:       ent.Name,
+		Prefix:     ent.Prefix,
+		Scopes:     scopes,
+		CreatedBy:  ent.CreatedBy,
+		ExpiresAt:  formatOptionalTime(ent.ExpiresAt),
+		LastUsedAt: formatOptionalTime(ent.LastUsedAt),
+		RevokedAt:  formatOptionalTime(ent.RevokedAt),
+		CreatedAt:  ent.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -1,34 +1,92 @@
 package dto
 
 import (
+	"context"
+	"time"
+
 	"github.com/IlhamSetiaji/report-converter/config"
 	"github.com/IlhamSetiaji/report-converter/entity"
 	"github.com/IlhamSetiaji/report-converter/logger"
 	"github.com/IlhamSetiaji/report-converter/response"
+	"github.com/IlhamSetiaji/report-converter/signer"
 )
 
 type ITemplateDTO interface {
-	ConvertEntityToResponse(ent *entity.Template) *response.TemplateResponse
+	ConvertEntityToResponse(ctx context.Context, ent *entity.Template) *response.TemplateResponse
+	ConvertVersionEntityToResponse(ctx context.Context, ent *entity.TemplateVersion, currentVersion int) *response.TemplateVersionResponse
 }
 
 type TemplateDTO struct {
 	config config.Config
 	logger logger.Logger
+	signer signer.URLSigner
 }
 
-func NewTemplateDTO(config config.Config, logger logger.Logger) ITemplateDTO {
+func NewTemplateDTO(config config.Config, logger logger.Logger, signer signer.URLSigner) ITemplateDTO {
 	return &TemplateDTO{
 		config: config,
 		logger: logger,
+		signer: signer,
 	}
 }
 
-func (t *TemplateDTO) ConvertEntityToResponse(ent *entity.Template) *response.TemplateResponse {
+func (t *TemplateDTO) ConvertEntityToResponse(ctx context.Context, ent *entity.Template) *response.TemplateResponse {
+	var approvedAt string
+	if ent.ApprovedAt != nil {
+		approvedAt = ent.ApprovedAt.Format(time.RFC3339)
+	}
+
+	tags := []string(ent.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+
 	return &response.TemplateResponse{
+		ID:               ent.ID.String(),
+		Name:             ent.Name,
+		TemplateType:     string(ent.TemplateType),
+		Path:             t.signPath(ctx, ent.Path),
+		PathOriginal:     ent.Path,
+		Description:      ent.Description,
+		Category:         ent.Category,
+		Tags:             tags,
+		Owner:            ent.Owner,
+		SampleData:       ent.SampleData,
+		OutputFormat:     ent.OutputFormat,
+		Version:          ent.CurrentVersion,
+		PublishedVersion: ent.PublishedVersion,
+		Status:           string(ent.Status),
+		ApprovedBy:       ent.ApprovedBy,
+		ApprovedAt:       approvedAt,
+	}
+}
+
+func (t *TemplateDTO) ConvertVersionEntityToResponse(ctx context.Context, ent *entity.TemplateVersion, currentVersion int) *response.TemplateVersionResponse {
+	var publishedAt string
+	if ent.PublishedAt != nil {
+		publishedAt = ent.PublishedAt.Format(time.RFC3339)
+	}
+
+	return &response.TemplateVersionResponse{
 		ID:           ent.ID.String(),
-		Name:         ent.Name,
-		TemplateType: string(ent.TemplateType),
-		Path:         config.GetConfig().Server.Url + "/" + ent.Path,
+		TemplateID:   ent.TemplateID.String(),
+		Version:      ent.Version,
+		Path:         t.signPath(ctx, ent.Path),
 		PathOriginal: ent.Path,
+		Checksum:     ent.Checksum,
+		IsCurrent:    ent.Version == currentVersion,
+		PublishedAt:  publishedAt,
+		CreatedAt:    ent.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// signPath turns a storage key into a signed download link that expires after
+// download.expiry. Files are never exposed through a public URL.
+func (t *TemplateDTO) signPath(ctx context.Context, key string) string {
+	url, _, err := t.signer.Sign(key, t.config.Download.Expiry, false)
+	if err != nil {
+		t.logger.WithContext(ctx).WithError(err).Error("Failed to sign download URL")
+		return ""
 	}
+	return url
 }
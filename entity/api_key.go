@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ApiKey is a long-lived credential for service-to-service callers. Only the
+// SHA-256 hash of the key is stored; the plain key is shown once when it is
+// created or rotated.
+type ApiKey struct {
+	gorm.Model `json:"-"`
+	ID         uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey"`
+	TenantID   string      `json:"tenant_id" gorm:"type:varchar(64);not null;default:'default';index"`
+	Name       string      `json:"name" gorm:"type:varchar(255);not null"`
+	Prefix     string      `json:"prefix" gorm:"type:varchar(16);not null"`
+	KeyHash    string      `json:"-" gorm:"type:varchar(64);not null;uniqueIndex"`
+	Scopes     StringArray `json:"scopes" gorm:"type:jsonb;not null;default:'[]'"`
+	CreatedBy  string      `json:"created_by" gorm:"type:varchar(255)"`
+	ExpiresAt  *time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time  `json:"last_used_at"`
+	RevokedAt  *time.Time  `json:"revoked_at"`
+}
+
+func (a *ApiKey) BeforeCreate(tx *gorm.DB) (err error) {
+	a.ID = uuid.New()
+	loc, _ := time.LoadLocation("Asia/Jakarta")
+	a.CreatedAt = time.Now().In(loc)
+	a.UpdatedAt = time.Now().In(loc)
+	return nil
+}
+
+func (a *ApiKey) BeforeUpdate(tx *gorm.DB) (err error) {
+	loc, _ := time.LoadLocation("Asia/Jakarta")
+	a.UpdatedAt = time.Now().In(loc)
+	return nil
+}
+
+func (ApiKey) TableName() string {
+	return "api_keys"
+}
@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AuditAction string
+
+const (
+	AuditActionTemplateCreate      AuditAction = "template.create"
+	AuditActionTemplateUpdate      AuditAction = "template.update"
+	AuditActionTemplateReplaceFile AuditAction = "template.replace_file"
+	AuditActionTemplateRollback    AuditAction = "template.rollback"
+	AuditActionTemplateSubmit      AuditAction = "template.submit"
+	AuditActionTemplateApprove     AuditAction = "template.approve"
+	AuditActionTemplateReject      AuditAction = "template.reject"
+	AuditActionTemplateArchive     AuditAction = "template.archive"
+	AuditActionTemplateDelete      AuditAction = "template.delete"
+	AuditActionTemplateRestore     AuditAction = "template.restore"
+	AuditActionTemplatePurge       AuditAction = "template.purge"
+	AuditActionTemplateRender      AuditAction = "template.render"
+	AuditActionTemplatePreview     AuditAction = "template.preview"
+)
+
+// AuditLog is one entry of the append-only audit trail. Rows are only ever
+// inserted; the migration installs a trigger rejecting updates and deletes.
+// Render payloads are never stored, only their HMAC-SHA256 in DataHash, and
+// Details holds field names and settings rather than values.
+type AuditLog struct {
+	ID              uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey"`
+	TenantID        string      `json:"tenant_id" gorm:"type:varchar(64);not null;index:idx_audit_logs_tenant_id_created_at"`
+	Actor           string      `json:"actor" gorm:"type:varchar(255);not null;index"`
+	Action          AuditAction `json:"action" gorm:"type:varchar(64);not null;index"`
+	TemplateID      *uuid.UUID  `json:"template_id" gorm:"type:uuid;index"`
+	TemplateVersion int         `json:"template_version"`
+	IP              string      `json:"ip" gorm:"type:varchar(64)"`
+	UserAgent       string      `json:"user_agent" gorm:"type:text"`
+	DataHash        string      `json:"data_hash" gorm:"type:varchar(64)"`
+	Details         JSONMap     `json:"details" gorm:"type:jsonb"`
+	CreatedAt       time.Time   `json:"created_at" gorm:"not null;index:idx_audit_logs_tenant_id_created_at"`
+}
+
+func (a *AuditLog) BeforeCreate(tx *gorm.DB) (err error) {
+	a.ID = uuid.New()
+	loc, _ := time.LoadLocation("Asia/Jakarta")
+	a.CreatedAt = time.Now().In(loc)
+	return nil
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// DownloadNonce records that a single-use download link has been consumed.
+// Rows are only needed until the link would have expired anyway.
+type DownloadNonce struct {
+	Nonce      string    `json:"nonce" gorm:"type:varchar(64);primaryKey"`
+	ExpiresAt  time.Time `json:"expires_at" gorm:"not null;index"`
+	ConsumedAt time.Time `json:"consumed_at" gorm:"not null"`
+}
+
+func (DownloadNonce) TableName() string {
+	return "download_nonces"
+}
@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// RateLimitBucket is a token bucket shared by all instances. Allowed records
+// whether the last request against the bucket got a token.
+type RateLimitBucket struct {
+	Key       string    `json:"key" gorm:"type:varchar(255);primaryKey"`
+	Tokens    float64   `json:"tokens" gorm:"not null"`
+	Allowed   bool      `json:"allowed" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null;index"`
+}
+
+func (RateLimitBucket) TableName() string {
+	return "rate_limit_buckets"
+}
@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+type QuotaPeriod string
+
+const (
+	QuotaPeriodDay   QuotaPeriod = "day"
+	QuotaPeriodMonth QuotaPeriod = "month"
+)
+
+// RenderQuotaUsage counts the renders of a tenant in one day or month.
+type RenderQuotaUsage struct {
+	TenantID    string      `json:"tenant_id" gorm:"type:varchar(64);primaryKey"`
+	Period      QuotaPeriod `json:"period" gorm:"type:varchar(8);primaryKey"`
+	PeriodStart time.Time   `json:"period_start" gorm:"type:date;primaryKey"`
+	Count       int         `json:"count" gorm:"not null"`
+}
+
+func (RenderQuotaUsage) TableName() string {
+	return "render_quota_usages"
+}
@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RoleAssignment grants a built-in role to a subject within one tenant.
+// Subjects are JWT subjects or "apikey:<id>" for API keys.
+type RoleAssignment struct {
+	gorm.Model `json:"-"`
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	TenantID   string    `json:"tenant_id" gorm:"type:varchar(64);not null;uniqueIndex:idx_role_assignments_tenant_subject_role"`
+	Subject    string    `json:"subject" gorm:"type:varchar(255);not null;uniqueIndex:idx_role_assignments_tenant_subject_role"`
+	Role       string    `json:"role" gorm:"type:varchar(32);not null;uniqueIndex:idx_role_assignments_tenant_subject_role"`
+	GrantedBy  string    `json:"granted_by" gorm:"type:varchar(255)"`
+}
+
+func (r *RoleAssignment) BeforeCreate(tx *gorm.DB) (err error) {
+	r.ID = uuid.New()
+	loc, _ := time.LoadLocation("Asia/Jakarta")
+	r.CreatedAt = time.Now().In(loc)
+	r.UpdatedAt = time.Now().In(loc)
+	return nil
+}
+
+func (RoleAssignment) TableName() string {
+	return "role_assignments"
+}
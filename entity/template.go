@@ -7,19 +7,49 @@ import (
 	"gorm.io/gorm"
 )
 
-type TemplateType string
+type TemplateStatus string
 
 const (
-	TemplateTypeExcel TemplateType = "excel"
-	TemplateTypeDocx  TemplateType = "docx"
+	TemplateStatusDraft     TemplateStatus = "draft"
+	TemplateStatusInReview  TemplateStatus = "in_review"
+	TemplateStatusPublished TemplateStatus = "published"
+	TemplateStatusArchived  TemplateStatus = "archived"
 )
 
 type Template struct {
 	gorm.Model   `json:"-"`
 	ID           uuid.UUID    `json:"id" gorm:"type:uuid;primaryKey"`
+	TenantID     string       `json:"tenant_id" gorm:"type:varchar(64);not null;default:'default';index"`
 	Name         string       `json:"name" gorm:"type:varchar(255);not null"`
 	TemplateType TemplateType `json:"template_type" gorm:"type:varchar(255);not null"`
 	Path         string       `json:"path" gorm:"type:text;not null"`
+
+	Description  string      `json:"description" gorm:"type:text"`
+	Category     string      `json:"category" gorm:"type:varchar(100);index"`
+	Tags         StringArray `json:"tags" gorm:"type:jsonb;not null;default:'[]';index:idx_templates_tags,type:gin"`
+	Owner        string      `json:"owner" gorm:"type:varchar(255);index"`
+	SampleData   JSONMap     `json:"sample_data" gorm:"type:jsonb"`
+	OutputFormat string      `json:"output_format" gorm:"type:varchar(20);not null;default:pdf"`
+
+	// CurrentVersion is the working copy, which previews render. It normally
+	// points at the newest version but moves back on rollback.
+	CurrentVersion int `json:"current_version" gorm:"not null;default:1"`
+	// PublishedVersion is the version GeneratePDF renders when a request does
+	// not pin one: the current version as it was last approved, 0 before the
+	// first approval.
+	PublishedVersion int               `json:"published_version" gorm:"not null;default:0"`
+	Versions         []TemplateVersion `json:"versions,omitempty" gorm:"foreignKey:TemplateID;references:ID"`
+
+	// Rows that existed before the lifecycle was introduced were already live,
+	// hence the published column default. New uploads start as drafts.
+	Status     TemplateStatus `json:"status" gorm:"type:varchar(20);not null;default:published;index"`
+	ApprovedBy string         `json:"approved_by" gorm:"type:varchar(255)"`
+	ApprovedAt *time.Time     `json:"approved_at"`
+
+	// Content is the plain text of the current version, extracted on upload.
+	// SearchVector is maintained by Postgres from the name and content.
+	Content      string `json:"-" gorm:"type:text"`
+	SearchVector string `json:"-" gorm:"->;type:tsvector GENERATED ALWAYS AS (to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(content, ''))) STORED;index:idx_templates_search_vector,type:gin"`
 }
 
 func (t *Template) BeforeCreate(tx *gorm.DB) (err error) {
@@ -0,0 +1,105 @@
+package entity
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type TemplateType string
+
+const (
+	TemplateTypeExcel TemplateType = "excel"
+	TemplateTypeDocx  TemplateType = "docx"
+)
+
+// TemplateTypeSpec describes what the service can do with one template type.
+// It is the single source of truth for upload validation, rendering and the
+// output formats a caller may ask for.
+type TemplateTypeSpec struct {
+	Type TemplateType
+	// MimeTypes are the sniffed content types accepted on upload.
+	MimeTypes []string
+	// Extension is the canonical file extension, including the dot.
+	Extension string
+	// Renderer names the converter renderer that fills in the placeholders.
+	Renderer string
+	// OutputFormats lists the formats a rendered document can be delivered
+	// in. The first entry is the default.
+	OutputFormats []string
+	// Package marks Office Open XML packages, which are subject to the zip,
+	// macro and relationship checks on upload.
+	Package bool
+}
+
+var templateTypes = map[TemplateType]TemplateTypeSpec{
+	TemplateTypeDocx: {
+		Type:          TemplateTypeDocx,
+		MimeTypes:     []string{"application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		Extension:     ".docx",
+		Renderer:      "docx",
+		OutputFormats: []string{"pdf", "docx"},
+		Package:       true,
+	},
+	TemplateTypeExcel: {
+		Type:          TemplateTypeExcel,
+		MimeTypes:     []string{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		Extension:     ".xlsx",
+		Renderer:      "xlsx",
+		OutputFormats: []string{"pdf", "xlsx"},
+		Package:       true,
+	},
+}
+
+// LookupTemplateType returns the spec of a registered template type.
+func LookupTemplateType(templateType string) (TemplateTypeSpec, bool) {
+	spec, ok := templateTypes[TemplateType(templateType)]
+	return spec, ok
+}
+
+// TemplateTypes returns every registered template type ordered by name.
+func TemplateTypes() []TemplateTypeSpec {
+	specs := make([]TemplateTypeSpec, 0, len(templateTypes))
+	for _, spec := range templateTypes {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Type < specs[j].Type })
+	return specs
+}
+
+// IsOutputFormat reports whether any registered template type can deliver
+// documents in format.
+func IsOutputFormat(format string) bool {
+	for _, spec := range templateTypes {
+		if spec.SupportsOutputFormat(format) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultOutputFormat is the format documents are delivered in when neither
+// the request nor the template names one.
+func (s TemplateTypeSpec) DefaultOutputFormat() string {
+	return s.OutputFormats[0]
+}
+
+// CheckOutputFormat returns an error naming the supported formats if
+// documents of this type cannot be delivered in format.
+func (s TemplateTypeSpec) CheckOutputFormat(format string) error {
+	if !s.SupportsOutputFormat(format) {
+		return fmt.Errorf("template type %s supports %s", s.Type, strings.Join(s.OutputFormats, ", "))
+	}
+	return nil
+}
+
+// SupportsOutputFormat reports whether documents of this type can be
+// delivered in format.
+func (s TemplateTypeSpec) SupportsOutputFormat(format string) bool {
+	for _, f := range s.OutputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TemplateVersion is an immutable snapshot of a template file. A new row is
+// written every time the file is replaced and its file is never changed, so a
+// document can always be re-rendered from the exact file it was issued with.
+// PublishedAt is the only column set later, the first time the version is
+// approved.
+type TemplateVersion struct {
+	gorm.Model `json:"-"`
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	TenantID   string    `json:"tenant_id" gorm:"type:varchar(64);not null;default:'default';index"`
+	TemplateID uuid.UUID `json:"template_id" gorm:"type:uuid;not null;uniqueIndex:idx_template_versions_template_id_version"`
+	Version    int       `json:"version" gorm:"not null;uniqueIndex:idx_template_versions_template_id_version"`
+	Path       string    `json:"path" gorm:"type:text;not null"`
+	Checksum   string    `json:"checksum" gorm:"type:varchar(64);not null"`
+	// PublishedAt is nil for versions that were never approved; only
+	// published versions can be generated from.
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+func (t *TemplateVersion) BeforeCreate(tx *gorm.DB) (err error) {
+	t.ID = uuid.New()
+	loc, _ := time.LoadLocation("Asia/Jakarta")
+	t.CreatedAt = time.Now().In(loc)
+	t.UpdatedAt = time.Now().In(loc)
+	return nil
+}
+
+func (TemplateVersion) TableName() string {
+	return "template_versions"
+}
@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// StringArray is a list of strings stored as a JSONB array.
+type StringArray []string
+
+func (a StringArray) Value() (driver.Value, error) {
+	if a == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(a)
+	return string(b), err
+}
+
+func (a *StringArray) Scan(value interface{}) error {
+	return scanJSON(value, a)
+}
+
+// JSONMap is a free-form JSON object stored as JSONB.
+type JSONMap map[string]interface{}
+
+func (m JSONMap) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(m)
+	return string(b), err
+}
+
+func (m *JSONMap) Scan(value interface{}) error {
+	return scanJSON(value, m)
+}
+
+func scanJSON(value interface{}, dest interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	default:
+		return fmt.Errorf("cannot scan %T into %T", value, dest)
+	}
+}
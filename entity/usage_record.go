@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UsageRecord meters one document generation for internal charging.
+type UsageRecord struct {
+	ID              uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	TenantID        string    `json:"tenant_id" gorm:"type:varchar(64);not null;index:idx_usage_records_tenant_id_created_at"`
+	TemplateID      uuid.UUID `json:"template_id" gorm:"type:uuid;not null;index"`
+	TemplateVersion int       `json:"template_version" gorm:"not null"`
+	Engine          string    `json:"engine" gorm:"type:varchar(64);not null"`
+	OutputFormat    string    `json:"output_format" gorm:"type:varchar(16);not null"`
+	Preview         bool      `json:"preview" gorm:"not null;default:false"`
+	PageCount       int       `json:"page_count" gorm:"not null"`
+	OutputBytes     int64     `json:"output_bytes" gorm:"not null"`
+	CPUMillis       int64     `json:"cpu_millis" gorm:"column:cpu_millis;not null"`
+	WallMillis      int64     `json:"wall_millis" gorm:"not null"`
+	CreatedAt       time.Time `json:"created_at" gorm:"not null;index:idx_usage_records_tenant_id_created_at"`
+}
+
+func (u *UsageRecord) BeforeCreate(tx *gorm.DB) (err error) {
+	u.ID = uuid.New()
+	loc, _ := time.LoadLocation("Asia/Jakarta")
+	u.CreatedAt = time.Now().In(loc)
+	return nil
+}
+
+func (UsageRecord) TableName() string {
+	return "usage_records"
+}
@@ -0,0 +1,142 @@
+package extractor
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/IlhamSetiaji/report-converter/utils"
+	"golang.org/x/net/html"
+)
+
+// ErrUnsupportedFormat is returned for files whose text cannot be extracted.
+var ErrUnsupportedFormat = errors.New("unsupported format for text extraction")
+
+// maxPackageSize bounds the bytes decompressed from one package. The upload
+// guard holds new files to the same default, but stored files are not
+// assumed to have passed it.
+var maxPackageSize int64 = 100 << 20
+
+// ExtractText returns the plain text of a DOCX, XLSX or HTML file so it can
+// be indexed for full-text search. The format is chosen from the extension
+// of name.
+func ExtractText(name string, data []byte) (string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".docx":
+		return extractOOXML(data, isDocxPart, "p")
+	case ".xlsx":
+		return extractOOXML(data, isXlsxPart, "si", "c")
+	case ".html", ".htm":
+		return extractHTML(bytes.NewReader(data))
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+func isDocxPart(name string) bool {
+	if name == "word/document.xml" || name == "word/footnotes.xml" || name == "word/endnotes.xml" {
+		return true
+	}
+	return strings.HasPrefix(name, "word/header") || strings.HasPrefix(name, "word/footer")
+}
+
+func isXlsxPart(name string) bool {
+	return name == "xl/sharedStrings.xml" || strings.HasPrefix(name, "xl/worksheets/sheet")
+}
+
+// extractOOXML concatenates the character data of the matching parts of an
+// Office Open XML package. A newline is written after every element named in
+// breakOn so paragraphs and cells do not run together.
+func extractOOXML(data []byte, match func(string) bool, breakOn ...string) (string, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+
+	var parts []*zip.File
+	for _, f := range r.File {
+		if match(f.Name) {
+			parts = append(parts, f)
+		}
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Name < parts[j].Name })
+
+	var sb strings.Builder
+	remaining := maxPackageSize
+	for _, part := range parts {
+		rc, err := part.Open()
+		if err != nil {
+			return "", err
+		}
+		limited := utils.LimitReader(rc, remaining)
+		err = extractXML(limited, &sb, breakOn)
+		rc.Close()
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", part.Name, err)
+		}
+		remaining = limited.N
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+func extractXML(r io.Reader, sb *strings.Builder, breakOn []string) error {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.CharData:
+			sb.Write(t)
+		case xml.EndElement:
+			for _, name := range breakOn {
+				if t.Name.Local == name {
+					sb.WriteByte('\n')
+					break
+				}
+			}
+		}
+	}
+}
+
+func extractHTML(r io.Reader) (string, error) {
+	var sb strings.Builder
+	tokenizer := html.NewTokenizer(r)
+	skip := 0
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if errors.Is(tokenizer.Err(), io.EOF) {
+				return strings.TrimSpace(sb.String()), nil
+			}
+			return "", tokenizer.Err()
+		case html.StartTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "script" || string(name) == "style" {
+				skip++
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "script" || string(name) == "style" {
+				if skip > 0 {
+					skip--
+				}
+			} else {
+				sb.WriteByte('\n')
+			}
+		case html.TextToken:
+			if skip == 0 {
+				sb.Write(tokenizer.Text())
+			}
+		}
+	}
+}
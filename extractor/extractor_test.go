@@ -0,0 +1,82 @@
+package extractor
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"hash/crc32"
+	"strings"
+	"testing"
+
+	"github.com/IlhamSetiaji/report-converter/utils"
+)
+
+// buildDocx writes a package holding word/document.xml. A non-zero declared
+// size replaces the real one in the headers.
+func buildDocx(t *testing.T, document []byte, declared uint64) []byte {
+	t.Helper()
+
+	var compressed bytes.Buffer
+	fw, err := flate.NewWriter(&compressed, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(document)
+	fw.Close()
+
+	size := uint64(len(document))
+	if declared > 0 {
+		size = declared
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	raw, err := w.CreateRaw(&zip.FileHeader{
+		Name:               "word/document.xml",
+		Method:             zip.Deflate,
+		CRC32:              crc32.ChecksumIEEE(document),
+		CompressedSize64:   uint64(compressed.Len()),
+		UncompressedSize64: size,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw.Write(compressed.Bytes())
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// paragraphs returns a document of n paragraphs reading "text".
+func paragraphs(n int) []byte {
+	return []byte("<document>" + strings.Repeat("<p>text</p>", n) + "</document>")
+}
+
+func TestExtractTextDocx(t *testing.T) {
+	text, err := ExtractText("report.docx", buildDocx(t, paragraphs(2), 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "text\ntext" {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestExtractTextRejectsForgedSize(t *testing.T) {
+	_, err := ExtractText("report.docx", buildDocx(t, paragraphs(100000), 64))
+	if err == nil {
+		t.Fatal("extracted a part that expands past its declared size")
+	}
+}
+
+func TestExtractTextStopsAtPackageLimit(t *testing.T) {
+	defer func(limit int64) { maxPackageSize = limit }(maxPackageSize)
+	maxPackageSize = 64 << 10
+
+	_, err := ExtractText("report.docx", buildDocx(t, paragraphs(100000), 0))
+	if !errors.Is(err, utils.ErrLimitExceeded) {
+		t.Fatalf("err = %v, want ErrLimitExceeded", err)
+	}
+}
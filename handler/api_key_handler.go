@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/IlhamSetiaji/report-converter/apperror"
+	"github.com/IlhamSetiaji/report-converter/auth"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/request"
+	"github.com/IlhamSetiaji/report-converter/usecase"
+	"github.com/IlhamSetiaji/report-converter/utils"
+	"github.com/IlhamSetiaji/report-converter/validator"
+	"github.com/gin-gonic/gin"
+)
+
+type IApiKeyHandler interface {
+	CreateApiKey(ctx *gin.Context)
+	FindAllApiKey(ctx *gin.Context)
+	RotateApiKey(ctx *gin.Context)
+	RevokeApiKey(ctx *gin.Context)
+}
+
+type ApiKeyHandler struct {
+	apiKeyUseCase usecase.IApiKeyUseCase
+	logger        logger.Logger
+	validator     validator.Validator
+}
+
+func NewApiKeyHandler(
+	apiKeyUseCase usecase.IApiKeyUseCase,
+	logger logger.Logger,
+	validator validator.Validator,
+) IApiKeyHandler {
+	return &ApiKeyHandler{
+		apiKeyUseCase: apiKeyUseCase,
+		logger:        logger,
+		validator:     validator,
+	}
+}
+
+// CreateApiKey issues a new key. The plain key is only part of this
+// response.
+func (h *ApiKeyHandler) CreateApiKey(ctx *gin.Context) {
+	var req request.CreateApiKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperror.Validation("Invalid request", err))
+		return
+	}
+
+	if err := h.validator.GetValidator().Struct(req); err != nil {
+		ctx.Error(apperror.Validation("Validation error", err))
+		return
+	}
+
+	apiKey, err := h.apiKeyUseCase.CreateApiKey(ctx.Request.Context(), &req, auth.GetPrincipal(ctx))
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to create API key"))
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusCreated, "API key created successfully", apiKey)
+}
+
+func (h *ApiKeyHandler) FindAllApiKey(ctx *gin.Context) {
+	apiKeys, err := h.apiKeyUseCase.FindAllApiKey(ctx.Request.Context())
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to find API keys"))
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "API keys found", apiKeys)
+}
+
+func (h *ApiKeyHandler) RotateApiKey(ctx *gin.Context) {
+	apiKey, err := h.apiKeyUseCase.RotateApiKey(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to rotate API key"))
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "API key rotated successfully", apiKey)
+}
+
+func (h *ApiKeyHandler) RevokeApiKey(ctx *gin.Context) {
+	apiKey, err := h.apiKeyUseCase.RevokeApiKey(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to revoke API key"))
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "API key revoked successfully", apiKey)
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/apperror"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/request"
+	"github.com/IlhamSetiaji/report-converter/usecase"
+	"github.com/IlhamSetiaji/report-converter/utils"
+	"github.com/IlhamSetiaji/report-converter/validator"
+	"github.com/gin-gonic/gin"
+)
+
+type IAuditHandler interface {
+	FindAuditLogs(ctx *gin.Context)
+	ExportAuditLogs(ctx *gin.Context)
+}
+
+type AuditHandler struct {
+	auditUseCase usecase.IAuditUseCase
+	logger       logger.Logger
+	validator    validator.Validator
+}
+
+func NewAuditHandler(
+	auditUseCase usecase.IAuditUseCase,
+	logger logger.Logger,
+	validator validator.Validator,
+) IAuditHandler {
+	return &AuditHandler{
+		auditUseCase: auditUseCase,
+		logger:       logger,
+		validator:    validator,
+	}
+}
+
+func (h *AuditHandler) FindAuditLogs(ctx *gin.Context) {
+	var req request.AuditLogListRequest
+	if !h.bindListRequest(ctx, &req) {
+		return
+	}
+
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+
+	logs, total, err := h.auditUseCase.FindAuditLogs(ctx.Request.Context(), &req)
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to find audit logs"))
+		return
+	}
+
+	pagination := utils.NewPagination(req.Page, req.Limit, total)
+	if len(logs) == 0 {
+		utils.PaginatedResponse(ctx, http.StatusOK, "No audit logs found", nil, pagination)
+		return
+	}
+
+	utils.PaginatedResponse(ctx, http.StatusOK, "Audit logs found successfully", logs, pagination)
+}
+
+// ExportAuditLogs streams the matching entries as a CSV attachment. Once
+// the first row is written the status can no longer change, so a failure
+// half way only ends the download early.
+func (h *AuditHandler) ExportAuditLogs(ctx *gin.Context) {
+	var req request.AuditLogListRequest
+	if !h.bindListRequest(ctx, &req) {
+		return
+	}
+
+	fileName := "audit-log-" + time.Now().Format("20060102-150405") + ".csv"
+	ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	ctx.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	ctx.Header("Cache-Control", "private, no-store")
+	ctx.Status(http.StatusOK)
+
+	if err := h.auditUseCase.ExportAuditLogs(ctx.Request.Context(), &req, ctx.Writer); err != nil {
+		h.logger.WithContext(ctx.Request.Context()).WithError(err).Error("Failed to export audit logs")
+	}
+}
+
+func (h *AuditHandler) bindListRequest(ctx *gin.Context, req *request.AuditLogListRequest) bool {
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		ctx.Error(apperror.Validation("Invalid request", err))
+		return false
+	}
+
+	if err := h.validator.GetValidator().Struct(req); err != nil {
+		ctx.Error(apperror.Validation("Validation error", err))
+		return false
+	}
+	return true
+}
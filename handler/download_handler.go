@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/IlhamSetiaji/report-converter/apperror"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/request"
+	"github.com/IlhamSetiaji/report-converter/usecase"
+	"github.com/IlhamSetiaji/report-converter/validator"
+	"github.com/gin-gonic/gin"
+)
+
+type IDownloadHandler interface {
+	Download(ctx *gin.Context)
+}
+
+type DownloadHandler struct {
+	downloadUseCase usecase.IDownloadUseCase
+	logger          logger.Logger
+	validator       validator.Validator
+}
+
+func NewDownloadHandler(
+	downloadUseCase usecase.IDownloadUseCase,
+	logger logger.Logger,
+	validator validator.Validator,
+) IDownloadHandler {
+	return &DownloadHandler{
+		downloadUseCase: downloadUseCase,
+		logger:          logger,
+		validator:       validator,
+	}
+}
+
+// Download serves a stored object through a signed, expiring link.
+func (h *DownloadHandler) Download(ctx *gin.Context) {
+	var req request.DownloadRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(apperror.Validation("Invalid request", err))
+		return
+	}
+
+	if err := h.validator.GetValidator().Struct(req); err != nil {
+		ctx.Error(apperror.Validation("Validation error", err))
+		return
+	}
+
+	file, err := h.downloadUseCase.OpenDownload(ctx.Request.Context(), &req)
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to download file"))
+		return
+	}
+	defer file.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(req.Key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(req.Key)}))
+	ctx.Header("Cache-Control", "private, no-store")
+	ctx.Status(http.StatusOK)
+	if _, err := io.Copy(ctx.Writer, file); err != nil {
+		h.logger.WithContext(ctx.Request.Context()).WithError(err).Error("Failed to stream download")
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/IlhamSetiaji/report-converter/health"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/response"
+	"github.com/gin-gonic/gin"
+)
+
+type IHealthHandler interface {
+	Livez(ctx *gin.Context)
+	Readyz(ctx *gin.Context)
+}
+
+type HealthHandler struct {
+	checker *health.Checker
+	logger  logger.Logger
+}
+
+func NewHealthHandler(checker *health.Checker, logger logger.Logger) IHealthHandler {
+	return &HealthHandler{
+		checker: checker,
+		logger:  logger,
+	}
+}
+
+// Livez reports that the process is up and serving. It checks no
+// dependencies, so an outage elsewhere never gets the service restarted.
+func (h *HealthHandler) Livez(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, response.HealthResponse{Status: "OK"})
+}
+
+// Readyz reports whether the service can take work: the database, storage
+// and LibreOffice must all be usable, and the server must not be shutting
+// down.
+func (h *HealthHandler) Readyz(ctx *gin.Context) {
+	results, ok := h.checker.Run(ctx.Request.Context())
+
+	res := response.HealthResponse{Status: "OK", Checks: map[string]response.HealthCheckResponse{}}
+	for _, result := range results {
+		check := response.HealthCheckResponse{Status: "OK"}
+		if result.Err != nil {
+			check.Status = "FAIL"
+			h.logger.WithContext(ctx.Request.Context()).
+				WithField("check", result.Name).
+				WithField("duration_ms", result.Duration.Milliseconds()).
+				WithError(result.Err).
+				Warn("Readiness check failed")
+		}
+		res.Checks[result.Name] = check
+	}
+
+	if !ok {
+		res.Status = "UNAVAILABLE"
+		ctx.JSON(http.StatusServiceUnavailable, res)
+		return
+	}
+	ctx.JSON(http.StatusOK, res)
+}
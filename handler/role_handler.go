@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/IlhamSetiaji/report-converter/apperror"
+	"github.com/IlhamSetiaji/report-converter/auth"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/request"
+	"github.com/IlhamSetiaji/report-converter/usecase"
+	"github.com/IlhamSetiaji/report-converter/utils"
+	"github.com/IlhamSetiaji/report-converter/validator"
+	"github.com/gin-gonic/gin"
+)
+
+type IRoleHandler interface {
+	FindRoles(ctx *gin.Context)
+	AssignRole(ctx *gin.Context)
+	FindRoleAssignments(ctx *gin.Context)
+	RevokeRole(ctx *gin.Context)
+}
+
+type RoleHandler struct {
+	roleUseCase usecase.IRoleUseCase
+	logger      logger.Logger
+	validator   validator.Validator
+}
+
+func NewRoleHandler(
+	roleUseCase usecase.IRoleUseCase,
+	logger logger.Logger,
+	validator validator.Validator,
+) IRoleHandler {
+	return &RoleHandler{
+		roleUseCase: roleUseCase,
+		logger:      logger,
+		validator:   validator,
+	}
+}
+
+func (h *RoleHandler) FindRoles(ctx *gin.Context) {
+	utils.SuccessResponse(ctx, http.StatusOK, "Roles found", h.roleUseCase.FindRoles())
+}
+
+func (h *RoleHandler) AssignRole(ctx *gin.Context) {
+	var req request.AssignRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperror.Validation("Invalid request", err))
+		return
+	}
+
+	if err := h.validator.GetValidator().Struct(req); err != nil {
+		ctx.Error(apperror.Validation("Validation error", err))
+		return
+	}
+
+	var grantedBy string
+	if principal := auth.GetPrincipal(ctx); principal != nil {
+		grantedBy = principal.Subject
+	}
+
+	assignment, err := h.roleUseCase.AssignRole(ctx.Request.Context(), &req, grantedBy)
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to assign role"))
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusCreated, "Role assigned successfully", assignment)
+}
+
+func (h *RoleHandler) FindRoleAssignments(ctx *gin.Context) {
+	var req request.RoleAssignmentListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(apperror.Validation("Invalid request", err))
+		return
+	}
+
+	if err := h.validator.GetValidator().Struct(req); err != nil {
+		ctx.Error(apperror.Validation("Validation error", err))
+		return
+	}
+
+	assignments, err := h.roleUseCase.FindRoleAssignments(ctx.Request.Context(), &req)
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to find role assignments"))
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Role assignments found", assignments)
+}
+
+func (h *RoleHandler) RevokeRole(ctx *gin.Context) {
+	revoked, err := h.roleUseCase.RevokeRole(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to revoke role"))
+		return
+	}
+
+	if !revoked {
+		ctx.Error(apperror.NotFound("Role assignment not found"))
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Role revoked successfully", nil)
+}
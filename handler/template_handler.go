@@ -1,25 +1,37 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
-	"runtime"
 	"strconv"
-	"strings"
 	"time"
 
+	"github.com/IlhamSetiaji/report-converter/apperror"
+	"github.com/IlhamSetiaji/report-converter/auth"
 	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/IlhamSetiaji/report-converter/converter"
 	"github.com/IlhamSetiaji/report-converter/entity"
 	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/metrics"
 	"github.com/IlhamSetiaji/report-converter/request"
+	"github.com/IlhamSetiaji/report-converter/response"
+	"github.com/IlhamSetiaji/report-converter/signer"
+	"github.com/IlhamSetiaji/report-converter/storage"
+	"github.com/IlhamSetiaji/report-converter/tenant"
+	"github.com/IlhamSetiaji/report-converter/upload"
 	"github.com/IlhamSetiaji/report-converter/usecase"
 	"github.com/IlhamSetiaji/report-converter/utils"
 	"github.com/IlhamSetiaji/report-converter/validator"
 	"github.com/gin-gonic/gin"
-	"github.com/nguyenthenguyen/docx"
+	"github.com/google/uuid"
 )
 
 type ITemplateHandler interface {
@@ -28,50 +40,83 @@ type ITemplateHandler interface {
 	FindTemplateByID(ctx *gin.Context)
 	DeleteTemplateByID(ctx *gin.Context)
 	GeneratePDF(ctx *gin.Context)
+	ReplaceTemplateFile(ctx *gin.Context)
+	FindTemplateVersions(ctx *gin.Context)
+	RollbackTemplate(ctx *gin.Context)
+	PreviewPDF(ctx *gin.Context)
+	SubmitTemplate(ctx *gin.Context)
+	ApproveTemplate(ctx *gin.Context)
+	RejectTemplate(ctx *gin.Context)
+	ArchiveTemplate(ctx *gin.Context)
+	SearchTemplates(ctx *gin.Context)
+	UpdateTemplateMetadata(ctx *gin.Context)
+	RestoreTemplateByID(ctx *gin.Context)
+	PurgeTemplateByID(ctx *gin.Context)
+	PurgeExpiredTemplates(ctx *gin.Context)
+	CreateDownloadURL(ctx *gin.Context)
+	FindTemplateTypes(ctx *gin.Context)
 }
 
+const (
+	templateDir  = "storage/templates"
+	generatedDir = "storage/generated"
+)
+
 type TemplateHandler struct {
 	templateUseCase usecase.ITemplateUseCase
+	usageUseCase    usecase.IUsageUseCase
 	logger          logger.Logger
 	validator       validator.Validator
 	config          config.Config
+	storage         storage.Storage
+	signer          signer.URLSigner
+	uploadGuard     upload.Guard
 }
 
 func NewTemplateHandler(
 	templateUseCase usecase.ITemplateUseCase,
+	usageUseCase usecase.IUsageUseCase,
 	logger logger.Logger,
 	validator validator.Validator,
 	config config.Config,
+	storage storage.Storage,
+	signer signer.URLSigner,
+	uploadGuard upload.Guard,
 ) ITemplateHandler {
 	return &TemplateHandler{
 		templateUseCase: templateUseCase,
+		usageUseCase:    usageUseCase,
 		logger:          logger,
 		validator:       validator,
 		config:          config,
+		storage:         storage,
+		signer:          signer,
+		uploadGuard:     uploadGuard,
 	}
 }
 
 func (h *TemplateHandler) CreateTemplate(ctx *gin.Context) {
-	h.logger.GetLogger().Info("Creating template")
+	h.logger.WithContext(ctx.Request.Context()).Info("Creating template")
+	h.limitUploadBody(ctx)
 	var req request.TemplateRequest
 	if err := ctx.ShouldBind(&req); err != nil {
-		h.logger.GetLogger().Error("Failed to bind JSON", err)
-		utils.BadRequestResponse(ctx, err.Error(), err.Error())
+		if maxBytesErr := new(http.MaxBytesError); errors.As(err, &maxBytesErr) {
+			ctx.Error(uploadError(err))
+			return
+		}
+		ctx.Error(apperror.Validation("Invalid request", err))
 		return
 	}
 
 	if err := h.validator.GetValidator().Struct(req); err != nil {
-		h.logger.GetLogger().Error("Validation error", err)
-		utils.BadRequestResponse(ctx, "Validation error", err.Error())
+		ctx.Error(apperror.Validation("Validation error", err))
 		return
 	}
 
 	if req.File != nil {
-		timestamp := time.Now().UnixNano()
-		filePath := "storage/templates/" + strconv.FormatInt(timestamp, 10) + "_" + req.File.Filename
-		if err := ctx.SaveUploadedFile(req.File, filePath); err != nil {
-			h.logger.GetLogger().Error("failed to save cover file: ", err)
-			utils.ErrorResponse(ctx, http.StatusInternalServerError, "failed to save cover file", err.Error())
+		filePath, err := h.saveTemplateFile(ctx, req.File, req.TemplateType)
+		if err != nil {
+			ctx.Error(uploadError(err))
 			return
 		}
 
@@ -79,95 +124,272 @@ func (h *TemplateHandler) CreateTemplate(ctx *gin.Context) {
 		req.Path = filePath
 	}
 
-	templateResponse, err := h.templateUseCase.CreateTemplate(&req)
+	templateResponse, err := h.templateUseCase.CreateTemplate(ctx.Request.Context(), &req)
 	if err != nil {
-		h.logger.GetLogger().Error("Failed to create template", err)
-		utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to create template", err.Error())
+		if req.Path != "" {
+			h.discardTemplateFile(ctx, req.Path)
+		}
+		ctx.Error(apperror.Wrap(err, "Failed to create template"))
 		return
 	}
 
 	utils.SuccessResponse(ctx, http.StatusCreated, "Template created successfully", templateResponse)
 }
 
+// FindTemplateTypes lists the registered template types so clients know what
+// they can upload and which output formats each type can be rendered to.
+func (h *TemplateHandler) FindTemplateTypes(ctx *gin.Context) {
+	var types []*response.TemplateTypeResponse
+	for _, spec := range entity.TemplateTypes() {
+		types = append(types, &response.TemplateTypeResponse{
+			Type:          string(spec.Type),
+			MimeTypes:     spec.MimeTypes,
+			Extension:     spec.Extension,
+			OutputFormats: spec.OutputFormats,
+		})
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Template types found successfully", types)
+}
+
 func (h *TemplateHandler) FindAllTemplate(ctx *gin.Context) {
-	h.logger.GetLogger().Info("Finding all templates")
-	templates, err := h.templateUseCase.FindAllTemplate()
+	h.logger.WithContext(ctx.Request.Context()).Info("Finding all templates")
+	var req request.TemplateListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(apperror.Validation("Invalid request", err))
+		return
+	}
+
+	if err := h.validator.GetValidator().Struct(req); err != nil {
+		ctx.Error(apperror.Validation("Validation error", err))
+		return
+	}
+
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+	if req.Sort == "" {
+		req.Sort = "created_at"
+		if req.Order == "" {
+			req.Order = "desc"
+		}
+	}
+
+	templates, total, err := h.templateUseCase.FindAllTemplate(ctx.Request.Context(), &req)
 	if err != nil {
-		h.logger.GetLogger().Error("Failed to find all templates", err)
-		utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to find all templates", err.Error())
+		ctx.Error(apperror.Wrap(err, "Failed to find all templates"))
 		return
 	}
 
+	pagination := utils.NewPagination(req.Page, req.Limit, total)
 	if len(templates) == 0 {
-		utils.SuccessResponse(ctx, http.StatusOK, "No templates found", nil)
+		utils.PaginatedResponse(ctx, http.StatusOK, "No templates found", nil, pagination)
 		return
 	}
 
-	utils.SuccessResponse(ctx, http.StatusOK, "Templates found successfully", templates)
+	utils.PaginatedResponse(ctx, http.StatusOK, "Templates found successfully", templates, pagination)
+}
+
+func (h *TemplateHandler) SearchTemplates(ctx *gin.Context) {
+	h.logger.WithContext(ctx.Request.Context()).Info("Searching templates")
+	var req request.TemplateSearchRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(apperror.Validation("Invalid request", err))
+		return
+	}
+
+	if err := h.validator.GetValidator().Struct(req); err != nil {
+		ctx.Error(apperror.Validation("Validation error", err))
+		return
+	}
+
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+
+	templates, total, err := h.templateUseCase.SearchTemplates(ctx.Request.Context(), &req)
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to search templates"))
+		return
+	}
+
+	pagination := utils.NewPagination(req.Page, req.Limit, total)
+	if len(templates) == 0 {
+		utils.PaginatedResponse(ctx, http.StatusOK, "No templates found", nil, pagination)
+		return
+	}
+
+	utils.PaginatedResponse(ctx, http.StatusOK, "Templates found successfully", templates, pagination)
 }
 
 func (h *TemplateHandler) FindTemplateByID(ctx *gin.Context) {
-	h.logger.GetLogger().Info("Finding template by ID")
+	h.logger.WithContext(ctx.Request.Context()).Info("Finding template by ID")
 	id := ctx.Param("id")
-	template, err := h.templateUseCase.FindTemplateByID(id)
+	template, err := h.templateUseCase.FindTemplateByID(ctx.Request.Context(), id)
 	if err != nil {
-		h.logger.GetLogger().Error("Failed to find template by ID", err)
-		utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to find template by ID", err.Error())
+		ctx.Error(apperror.Wrap(err, "Failed to find template by ID"))
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Template found successfully", template)
+}
+
+func (h *TemplateHandler) UpdateTemplateMetadata(ctx *gin.Context) {
+	h.logger.WithContext(ctx.Request.Context()).Info("Updating template metadata")
+	id := ctx.Param("id")
+	var req request.UpdateTemplateMetadataRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperror.Validation("Invalid request", err))
+		return
+	}
+
+	if err := h.validator.GetValidator().Struct(req); err != nil {
+		ctx.Error(apperror.Validation("Validation error", err))
 		return
 	}
 
-	if template == nil {
-		utils.SuccessResponse(ctx, http.StatusOK, "Template not found", nil)
+	template, err := h.templateUseCase.UpdateTemplateMetadata(ctx.Request.Context(), id, &req)
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to update template metadata"))
 		return
 	}
 
-	utils.SuccessResponse(ctx, http.StatusOK, "Template found successfully", template)
+	utils.SuccessResponse(ctx, http.StatusOK, "Template updated successfully", template)
 }
 
 func (h *TemplateHandler) DeleteTemplateByID(ctx *gin.Context) {
-	h.logger.GetLogger().Info("Deleting template by ID")
+	h.logger.WithContext(ctx.Request.Context()).Info("Deleting template by ID")
 	id := ctx.Param("id")
-	err := h.templateUseCase.DeleteTemplateByID(id)
+	err := h.templateUseCase.DeleteTemplateByID(ctx.Request.Context(), id)
 	if err != nil {
-		h.logger.GetLogger().Error("Failed to delete template by ID", err)
-		utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to delete template by ID", err.Error())
+		ctx.Error(apperror.Wrap(err, "Failed to delete template by ID"))
 		return
 	}
 
 	utils.SuccessResponse(ctx, http.StatusOK, "Template deleted successfully", nil)
 }
 
+func (h *TemplateHandler) RestoreTemplateByID(ctx *gin.Context) {
+	h.logger.WithContext(ctx.Request.Context()).Info("Restoring template by ID")
+	template, err := h.templateUseCase.RestoreTemplateByID(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to restore template by ID"))
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Template restored successfully", template)
+}
+
+func (h *TemplateHandler) PurgeTemplateByID(ctx *gin.Context) {
+	h.logger.WithContext(ctx.Request.Context()).Info("Purging template by ID")
+	template, err := h.templateUseCase.PurgeTemplateByID(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to purge template by ID"))
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Template purged successfully", template)
+}
+
+// PurgeExpiredTemplates runs the same retention-based purge as the background
+// scheduler, for operators who do not want to wait for the next run.
+func (h *TemplateHandler) PurgeExpiredTemplates(ctx *gin.Context) {
+	h.logger.WithContext(ctx.Request.Context()).Info("Purging expired templates")
+	purged, err := h.templateUseCase.PurgeExpiredTemplates(ctx.Request.Context(), h.config.Storage.TrashRetention)
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to purge expired templates"))
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Expired templates purged successfully", gin.H{"purged": purged})
+}
+
+// CreateDownloadURL issues a signed link to a template file. Links expire
+// after download.expiry unless expires_in is given and can be single-use.
+func (h *TemplateHandler) CreateDownloadURL(ctx *gin.Context) {
+	h.logger.WithContext(ctx.Request.Context()).Info("Creating template download URL")
+	var req request.DownloadURLRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(apperror.Validation("Invalid request", err))
+		return
+	}
+
+	if err := h.validator.GetValidator().Struct(req); err != nil {
+		ctx.Error(apperror.Validation("Validation error", err))
+		return
+	}
+
+	if req.ExpiresIn == 0 {
+		req.ExpiresIn = int(h.config.Download.Expiry.Seconds())
+	}
+
+	downloadUrl, err := h.templateUseCase.CreateDownloadURL(ctx.Request.Context(), ctx.Param("id"), &req)
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to create download URL"))
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Download URL created successfully", downloadUrl)
+}
+
+// GeneratePDF renders a published template. Drafts and templates under
+// review can only be rendered through PreviewPDF.
 func (h *TemplateHandler) GeneratePDF(c *gin.Context) {
+	h.renderPDF(c, true)
+}
+
+// PreviewPDF renders a template regardless of its lifecycle status so that
+// drafts can be checked before they are submitted for review.
+func (h *TemplateHandler) PreviewPDF(c *gin.Context) {
+	h.renderPDF(c, false)
+}
+
+func (h *TemplateHandler) renderPDF(c *gin.Context, requirePublished bool) {
 	var req request.GeneratePDFRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.GetLogger().Error("Failed to bind JSON", err)
-		utils.BadRequestResponse(c, "Invalid request", err.Error())
+		c.Error(apperror.Validation("Invalid request", err))
 		return
 	}
 
-	template, err := h.templateUseCase.FindTemplateByID(req.TemplateID)
+	template, err := h.templateUseCase.ResolveTemplate(c.Request.Context(), req.TemplateID, requirePublished)
 	if err != nil {
-		h.logger.GetLogger().Error("Failed to find template by ID", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to find template", err.Error())
+		c.Error(apperror.Wrap(err, "Failed to find template"))
 		return
 	}
+	c.Request = c.Request.WithContext(logger.WithField(c.Request.Context(), logger.FieldTemplateID, template.ID))
 
-	if template == nil {
-		h.logger.GetLogger().Error("Template not found")
-		utils.ErrorResponse(c, http.StatusNotFound, "Template not found", "Template not found")
+	spec, ok := entity.LookupTemplateType(template.TemplateType)
+	if !ok {
+		c.Error(apperror.UnsupportedType("Invalid template type", fmt.Errorf("unknown template type %s", template.TemplateType)))
 		return
 	}
 
-	if template.TemplateType != string(entity.TemplateTypeDocx) {
-		h.logger.GetLogger().Error("Invalid template type")
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid template type", "Invalid template type")
+	outputFormat := req.OutputFormat
+	if outputFormat == "" {
+		outputFormat = template.OutputFormat
+	}
+	if outputFormat == "" {
+		outputFormat = spec.DefaultOutputFormat()
+	}
+	if err := spec.CheckOutputFormat(outputFormat); err != nil {
+		c.Error(apperror.Validation("Unsupported output format", err))
 		return
 	}
 
 	templatePath := template.PathOriginal
-	if _, err := os.Stat(templatePath); os.IsNotExist(err) {
-		h.logger.GetLogger().Error("Template file does not exist", err)
-		utils.ErrorResponse(c, http.StatusNotFound, "Template file not found", "Template file not found")
+	exists, err := h.storage.Exists(c.Request.Context(), templatePath)
+	if err != nil {
+		c.Error(apperror.Wrap(err, "Failed to check template file"))
+		return
+	}
+	if !exists {
+		c.Error(apperror.NotFound("Template file not found"))
 		return
 	}
 
@@ -178,147 +400,404 @@ func (h *TemplateHandler) GeneratePDF(c *gin.Context) {
 		} else if intValue, ok := value.(float64); ok {
 			data[key] = strconv.FormatFloat(intValue, 'f', -1, 64)
 		} else {
-			h.logger.GetLogger().Error("Invalid data type for key", key)
-			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid data type", fmt.Sprintf("Invalid data type for key %s", key))
+			c.Error(apperror.Validation("Invalid data type", fmt.Errorf("invalid data type for key %s", key)))
 			return
 		}
 	}
 
 	// Process the document
-	pdfPath, err := h.processDocument(templatePath, data)
+	started := time.Now()
+	output, cleanup, err := h.processDocument(c.Request.Context(), spec, templatePath, data, outputFormat)
+	if err != nil {
+		c.Error(apperror.ConversionFailed(err))
+		return
+	}
+	defer cleanup()
+	outputPath := output.path
+	h.recordUsage(c.Request.Context(), template, output, outputFormat, time.Since(started), !requirePublished)
+
+	if err := h.templateUseCase.RecordRender(c.Request.Context(), template, req.Data, outputFormat, !requirePublished); err != nil {
+		c.Error(apperror.Wrap(err, "Failed to audit document generation"))
+		return
+	}
+
+	// Keep issued documents; previews are throwaway and are not stored.
+	if requirePublished {
+		outputKey := tenant.StorageKey(c.Request.Context(), generatedDir+"/"+uuid.NewString()+"."+outputFormat)
+		if err := h.storeFile(c.Request.Context(), outputPath, outputKey, mime.TypeByExtension("."+outputFormat)); err != nil {
+			c.Error(apperror.Wrap(err, "Failed to store generated document"))
+			return
+		}
+		outputUrl, _, err := h.signer.Sign(outputKey, h.config.Download.Expiry, false)
+		if err != nil {
+			c.Error(apperror.Wrap(err, "Failed to sign output URL"))
+			return
+		}
+		c.Header("X-Output-Url", outputUrl)
+	}
+
+	// Send the document as response
+	c.Header("X-Template-Version", strconv.Itoa(template.Version))
+	c.File(outputPath)
+}
+
+func (h *TemplateHandler) ReplaceTemplateFile(ctx *gin.Context) {
+	h.logger.WithContext(ctx.Request.Context()).Info("Replacing template file")
+	id := ctx.Param("id")
+	h.limitUploadBody(ctx)
+	var req request.ReplaceTemplateFileRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		if maxBytesErr := new(http.MaxBytesError); errors.As(err, &maxBytesErr) {
+			ctx.Error(uploadError(err))
+			return
+		}
+		ctx.Error(apperror.Validation("Invalid request", err))
+		return
+	}
+
+	if err := h.validator.GetValidator().Struct(req); err != nil {
+		ctx.Error(apperror.Validation("Validation error", err))
+		return
+	}
+
+	current, err := h.templateUseCase.FindTemplateByID(ctx.Request.Context(), id)
 	if err != nil {
-		h.logger.GetLogger().Error("Failed to process document ", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process document", err.Error())
+		ctx.Error(apperror.Wrap(err, "Failed to find template by ID"))
 		return
 	}
-	defer os.Remove(pdfPath)
 
-	// Send the PDF as response
-	c.File(pdfPath)
+	// A new version must keep the template's type.
+	filePath, err := h.saveTemplateFile(ctx, req.File, current.TemplateType)
+	if err != nil {
+		ctx.Error(uploadError(err))
+		return
+	}
+	req.File = nil
+	req.Path = filePath
+
+	template, err := h.templateUseCase.ReplaceTemplateFile(ctx.Request.Context(), id, &req)
+	if err != nil {
+		h.discardTemplateFile(ctx, filePath)
+		ctx.Error(apperror.Wrap(err, "Failed to replace template file"))
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusCreated, "Template version created successfully", template)
 }
 
-func (h *TemplateHandler) processDocument(templatePath string, data map[string]string) (string, error) {
-	// Read the docx file
-	r, err := docx.ReadDocxFile(templatePath)
+func (h *TemplateHandler) FindTemplateVersions(ctx *gin.Context) {
+	h.logger.WithContext(ctx.Request.Context()).Info("Finding template versions")
+	id := ctx.Param("id")
+	versions, err := h.templateUseCase.FindTemplateVersions(ctx.Request.Context(), id)
 	if err != nil {
-		return "", fmt.Errorf("failed to read document: %v", err)
+		ctx.Error(apperror.Wrap(err, "Failed to find template versions"))
+		return
+	}
+
+	if versions == nil {
+		utils.SuccessResponse(ctx, http.StatusOK, "No template versions found", nil)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Template versions found successfully", versions)
+}
+
+func (h *TemplateHandler) RollbackTemplate(ctx *gin.Context) {
+	h.logger.WithContext(ctx.Request.Context()).Info("Rolling back template")
+	id := ctx.Param("id")
+	var req request.RollbackTemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperror.Validation("Invalid request", err))
+		return
+	}
+
+	if err := h.validator.GetValidator().Struct(req); err != nil {
+		ctx.Error(apperror.Validation("Validation error", err))
+		return
+	}
+
+	template, err := h.templateUseCase.RollbackTemplate(ctx.Request.Context(), id, &req)
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to roll back template"))
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Template rolled back successfully", template)
+}
+
+func (h *TemplateHandler) SubmitTemplate(ctx *gin.Context) {
+	h.logger.WithContext(ctx.Request.Context()).Info("Submitting template for review")
+	template, err := h.templateUseCase.SubmitTemplate(ctx.Request.Context(), ctx.Param("id"))
+	h.writeTransitionResponse(ctx, template, err, "Template submitted for review")
+}
+
+func (h *TemplateHandler) ApproveTemplate(ctx *gin.Context) {
+	h.logger.WithContext(ctx.Request.Context()).Info("Approving template")
+	var req request.ReviewTemplateRequest
+	if !h.bindReviewRequest(ctx, &req) {
+		return
+	}
+
+	template, err := h.templateUseCase.ApproveTemplate(ctx.Request.Context(), ctx.Param("id"), &req)
+	h.writeTransitionResponse(ctx, template, err, "Template published successfully")
+}
+
+func (h *TemplateHandler) RejectTemplate(ctx *gin.Context) {
+	h.logger.WithContext(ctx.Request.Context()).Info("Rejecting template")
+	var req request.ReviewTemplateRequest
+	if !h.bindReviewRequest(ctx, &req) {
+		return
+	}
+
+	template, err := h.templateUseCase.RejectTemplate(ctx.Request.Context(), ctx.Param("id"), &req)
+	h.writeTransitionResponse(ctx, template, err, "Template returned to draft")
+}
+
+func (h *TemplateHandler) ArchiveTemplate(ctx *gin.Context) {
+	h.logger.WithContext(ctx.Request.Context()).Info("Archiving template")
+	template, err := h.templateUseCase.ArchiveTemplate(ctx.Request.Context(), ctx.Param("id"))
+	h.writeTransitionResponse(ctx, template, err, "Template archived successfully")
+}
+
+func (h *TemplateHandler) bindReviewRequest(ctx *gin.Context, req *request.ReviewTemplateRequest) bool {
+	// Authenticated reviewers may omit the body entirely.
+	if ctx.Request.ContentLength == 0 && auth.GetPrincipal(ctx) != nil {
+		req.ReviewedBy = auth.GetPrincipal(ctx).Subject
+		return true
+	}
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		ctx.Error(apperror.Validation("Invalid request", err))
+		return false
 	}
 
-	docxContent := r.Editable()
+	// The reviewer is the authenticated caller, not whatever the body claims.
+	if principal := auth.GetPrincipal(ctx); principal != nil {
+		req.ReviewedBy = principal.Subject
+	}
+
+	if err := h.validator.GetValidator().Struct(req); err != nil {
+		ctx.Error(apperror.Validation("Validation error", err))
+		return false
+	}
+	return true
+}
 
-	// Replace all variables in the content
-	content := docxContent.GetContent()
-	for key, value := range data {
-		templateVar := "{{." + key + "}}"
-		content = strings.ReplaceAll(content, templateVar, value)
+func (h *TemplateHandler) writeTransitionResponse(ctx *gin.Context, template *response.TemplateResponse, err error, message string) {
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to change template status"))
+		return
 	}
 
-	// Update the content
-	docxContent.SetContent(content)
+	utils.SuccessResponse(ctx, http.StatusOK, message, template)
+}
 
-	// Ensure the directory for generated PDFs exists
-	generatedPDFDir := "storage/generated_pdf"
-	if err := os.MkdirAll(generatedPDFDir, os.ModePerm); err != nil {
-		return "", fmt.Errorf("failed to create directory %s: %v", generatedPDFDir, err)
+// saveTemplateFile stores an uploaded template under a unique name. Every
+// upload gets its own file so earlier versions are never overwritten.
+// The client's file name and content type are never trusted: the upload guard
+// sniffs the content, checks it against the declared template type and
+// supplies a sanitised name.
+func (h *TemplateHandler) saveTemplateFile(ctx *gin.Context, file *multipart.FileHeader, templateType string) (string, error) {
+	if file.Size > h.uploadGuard.MaxSize() {
+		return "", upload.ErrTooLarge
 	}
 
-	// Save the modified DOCX
-	modifiedNamePath := "modified_" + filepath.Base(templatePath)
-	modifiedDocxPath := filepath.Join(generatedPDFDir, modifiedNamePath)
-	err = docxContent.WriteToFile(modifiedDocxPath)
+	src, err := file.Open()
 	if err != nil {
-		return "", fmt.Errorf("failed to save modified document: %v", err)
+		return "", err
 	}
-	defer os.Remove(modifiedDocxPath)
+	defer src.Close()
 
-	// Convert to PDF using LibreOffice
-	h.logger.GetLogger().Info("Converting to PDF: ", modifiedDocxPath)
-	err = convertToPDF(modifiedDocxPath, generatedPDFDir)
+	data, err := io.ReadAll(io.LimitReader(src, h.uploadGuard.MaxSize()+1))
 	if err != nil {
-		return "", fmt.Errorf("failed to convert to PDF: %v", err)
+		return "", err
 	}
 
-	// Construct the expected PDF file path
-	pdfFileName := strings.TrimSuffix(filepath.Base(modifiedDocxPath), filepath.Ext(modifiedDocxPath)) + ".pdf"
-	pdfPath := filepath.Join(generatedPDFDir, pdfFileName)
+	inspection, err := h.uploadGuard.Inspect(file.Filename, templateType, data)
+	if err != nil {
+		return "", err
+	}
 
-	// Verify the PDF was created
-	if _, err := os.Stat(pdfPath); os.IsNotExist(err) {
-		return "", fmt.Errorf("PDF file was not created")
+	timestamp := time.Now().UnixNano()
+	filePath := tenant.StorageKey(ctx.Request.Context(), templateDir+"/"+strconv.FormatInt(timestamp, 10)+"_"+inspection.FileName)
+	if err := storage.PutBytes(ctx.Request.Context(), h.storage, filePath, data, inspection.MimeType); err != nil {
+		return "", err
 	}
+	return filePath, nil
+}
+
+// discardTemplateFile removes an uploaded file that no template ended up
+// referencing. A failure only leaves an orphaned object behind, so it is
+// logged rather than returned.
+func (h *TemplateHandler) discardTemplateFile(ctx *gin.Context, path string) {
+	if err := h.storage.Delete(ctx.Request.Context(), path); err != nil {
+		h.logger.WithContext(ctx.Request.Context()).WithError(err).WithField("path", path).Warn("Failed to remove unused template file")
+	}
+}
 
-	return pdfPath, nil
+// limitUploadBody caps the request body a little above the upload limit so
+// oversized uploads are cut off while they are being read.
+func (h *TemplateHandler) limitUploadBody(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, h.uploadGuard.MaxSize()+1<<20)
 }
 
-func convertToPDF(docxPath, outputDir string) error {
-	// Try both direct command and container-specific paths
-	loPaths := []string{
-		"/usr/bin/soffice", // Linux default
-		"/usr/local/bin/soffice",
-		"/opt/libreoffice/program/soffice",
+// uploadError classifies a failed template upload.
+func uploadError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, upload.ErrTooLarge), errors.As(err, &maxBytesErr):
+		return apperror.WrapAs(err, apperror.CodePayloadTooLarge, "File too large")
+	case errors.Is(err, upload.ErrTypeMismatch):
+		return apperror.UnsupportedType("File type not allowed", err)
+	case errors.Is(err, upload.ErrUnsafe):
+		return apperror.WrapAs(err, apperror.CodeUnprocessable, "File rejected")
+	default:
+		return apperror.Wrap(err, "Failed to save template file")
 	}
+}
 
-	if runtime.GOOS == "windows" {
-		loPaths = append(loPaths, []string{
-			`C:\Program Files\LibreOffice\program\soffice.exe`,
-			`C:\Program Files (x86)\LibreOffice\program\soffice.exe`,
-		}...)
+// storeFile uploads a local file to storage under key.
+func (h *TemplateHandler) storeFile(ctx context.Context, localPath string, key string, contentType string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
 
-	var loPath string
-	for _, path := range loPaths {
-		if _, err := os.Stat(path); err == nil {
-			loPath = path
-			break
-		}
+	info, err := f.Stat()
+	if err != nil {
+		return err
 	}
+	return h.storage.Put(ctx, key, f, info.Size(), contentType)
+}
+
+// renderOutput is a rendered document together with what producing it cost.
+type renderOutput struct {
+	path    string
+	engine  string
+	cpuTime time.Duration
+}
 
-	if loPath == "" {
-		return fmt.Errorf("LibreOffice not found in standard locations")
+// processDocument renders a template from storage with the renderer of its
+// type and, for PDF output, converts the result with LibreOffice. All work
+// happens in a private temporary directory; the returned cleanup removes it,
+// including the output, once the caller is done.
+func (h *TemplateHandler) processDocument(
+	ctx context.Context,
+	spec entity.TemplateTypeSpec,
+	templatePath string,
+	data map[string]string,
+	outputFormat string,
+) (*renderOutput, func(), error) {
+	workDir, err := os.MkdirTemp("", "report-converter-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create work directory: %v", err)
 	}
+	cleanup := func() { os.RemoveAll(workDir) }
 
-	// Add container-specific environment variables
-	env := os.Environ()
-	env = append(env, "HOME=/tmp") // LibreOffice needs a home directory
+	started := time.Now()
+	output, err := h.renderDocument(ctx, workDir, spec, templatePath, data, outputFormat)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	metrics.RenderDuration.WithLabelValues(string(spec.Type), output.engine).Observe(time.Since(started).Seconds())
+	return output, cleanup, nil
+}
 
-	cmd := exec.Command(
-		loPath,
-		"--headless",
-		"--convert-to", "pdf",
-		"--outdir", outputDir,
-		docxPath,
-	)
-	cmd.Env = env
+func (h *TemplateHandler) renderDocument(
+	ctx context.Context,
+	workDir string,
+	spec entity.TemplateTypeSpec,
+	templatePath string,
+	data map[string]string,
+	outputFormat string,
+) (*renderOutput, error) {
+	renderer, ok := converter.Lookup(spec.Renderer)
+	if !ok {
+		return nil, fmt.Errorf("no renderer registered for template type %s", spec.Type)
+	}
 
-	// Log the paths being used
-	fmt.Printf("Using LibreOffice at: %s\n", loPath)
-	fmt.Printf("Input DOCX path: %s\n", docxPath)
-	fmt.Printf("Output directory: %s\n", outputDir)
+	// Fetch the template from storage, the renderers and LibreOffice need a
+	// local file
+	localTemplatePath := filepath.Join(workDir, path.Base(templatePath))
+	if err := h.fetchFile(ctx, templatePath, localTemplatePath); err != nil {
+		return nil, fmt.Errorf("failed to fetch template: %v", err)
+	}
 
-	// Set timeout for the conversion
-	done := make(chan error, 1)
-	go func() {
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			fmt.Printf("LibreOffice output: %s\n", string(output))
-			done <- fmt.Errorf("PDF conversion failed: %v, output: %s", err, string(output))
-			return
-		}
-		done <- nil
-	}()
+	renderedPath, err := renderer.Render(ctx, workDir, localTemplatePath, data)
+	if err != nil {
+		return nil, err
+	}
+	if outputFormat != "pdf" {
+		return &renderOutput{path: renderedPath, engine: spec.Renderer}, nil
+	}
 
-	select {
-	case err := <-done:
-		if err != nil {
-			return err
-		}
-	case <-time.After(30 * time.Second):
-		// Kill the process if it takes too long
-		if cmd.Process != nil {
-			cmd.Process.Kill()
-		}
-		return fmt.Errorf("PDF conversion timed out after 30 seconds")
+	// Convert to PDF using LibreOffice
+	h.logger.WithContext(ctx).WithField("path", renderedPath).Info("Converting to PDF")
+	conversion, err := converter.ConvertToPDF(ctx, renderedPath, workDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert to PDF: %v", err)
+	}
+	return &renderOutput{
+		path:    conversion.Path,
+		engine:  spec.Renderer + "+libreoffice",
+		cpuTime: conversion.CPUTime,
+	}, nil
+}
+
+// recordUsage meters a generation for charging. Metering must not cost the
+// caller a document that was already rendered, so failures are only logged.
+func (h *TemplateHandler) recordUsage(
+	ctx context.Context,
+	template *response.TemplateResponse,
+	output *renderOutput,
+	outputFormat string,
+	wallTime time.Duration,
+	preview bool,
+) {
+	info, err := os.Stat(output.path)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to stat rendered document")
+		return
+	}
+
+	pages, err := converter.CountPages(output.path, outputFormat)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Warn("Failed to count pages of rendered document")
+	}
+
+	err = h.usageUseCase.RecordRender(ctx, &usecase.RenderUsage{
+		TemplateID:      template.ID,
+		TemplateVersion: template.Version,
+		Engine:          output.engine,
+		OutputFormat:    outputFormat,
+		Preview:         preview,
+		PageCount:       pages,
+		OutputBytes:     info.Size(),
+		CPUTime:         output.cpuTime,
+		WallTime:        wallTime,
+	})
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to record usage")
 	}
+}
+
+// fetchFile copies the object at key into a local file.
+func (h *TemplateHandler) fetchFile(ctx context.Context, key string, localPath string) error {
+	src, err := h.storage.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
 
-	return nil
+	dst, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
 }
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/apperror"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/request"
+	"github.com/IlhamSetiaji/report-converter/usecase"
+	"github.com/IlhamSetiaji/report-converter/utils"
+	"github.com/IlhamSetiaji/report-converter/validator"
+	"github.com/gin-gonic/gin"
+)
+
+type IUsageHandler interface {
+	FindUsage(ctx *gin.Context)
+}
+
+type UsageHandler struct {
+	usageUseCase usecase.IUsageUseCase
+	logger       logger.Logger
+	validator    validator.Validator
+}
+
+func NewUsageHandler(
+	usageUseCase usecase.IUsageUseCase,
+	logger logger.Logger,
+	validator validator.Validator,
+) IUsageHandler {
+	return &UsageHandler{
+		usageUseCase: usageUseCase,
+		logger:       logger,
+		validator:    validator,
+	}
+}
+
+// FindUsage reports the caller's tenant usage per day or month, as the
+// usual JSON envelope or, with format=csv, as a CSV attachment.
+func (h *UsageHandler) FindUsage(ctx *gin.Context) {
+	var req request.UsageReportRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(apperror.Validation("Invalid request", err))
+		return
+	}
+
+	if err := h.validator.GetValidator().Struct(req); err != nil {
+		ctx.Error(apperror.Validation("Validation error", err))
+		return
+	}
+
+	if req.Format == "csv" {
+		fileName := "usage-" + time.Now().Format("20060102-150405") + ".csv"
+		ctx.Header("Content-Type", "text/csv; charset=utf-8")
+		ctx.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+		ctx.Status(http.StatusOK)
+		if err := h.usageUseCase.ExportUsage(ctx.Request.Context(), &req, ctx.Writer); err != nil {
+			h.logger.WithContext(ctx.Request.Context()).WithError(err).Error("Failed to export usage")
+		}
+		return
+	}
+
+	usage, err := h.usageUseCase.AggregateUsage(ctx.Request.Context(), &req)
+	if err != nil {
+		ctx.Error(apperror.Wrap(err, "Failed to aggregate usage"))
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Usage found successfully", usage)
+}
@@ -0,0 +1,66 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/IlhamSetiaji/report-converter/converter"
+	"github.com/IlhamSetiaji/report-converter/database"
+	"github.com/IlhamSetiaji/report-converter/storage"
+	"github.com/google/uuid"
+)
+
+// Database pings the connection pool.
+func Database(db database.Database) Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.GetDb().DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// storageProbePrefix is reserved for readiness probes; nothing else is
+// stored under it.
+const storageProbePrefix = ".health/"
+
+// Storage writes and removes a probe object, proving the backend is
+// reachable and writable. A read-only mount or a bucket without write
+// rights fails the check.
+func Storage(s storage.Storage) Check {
+	return func(ctx context.Context) error {
+		key := storageProbePrefix + uuid.NewString()
+		data := []byte("ok")
+		if err := s.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "text/plain"); err != nil {
+			return fmt.Errorf("write probe: %w", err)
+		}
+		if err := s.Delete(ctx, key); err != nil {
+			return fmt.Errorf("remove probe: %w", err)
+		}
+		return nil
+	}
+}
+
+// LibreOffice converts a small text document to PDF.
+func LibreOffice() Check {
+	return func(ctx context.Context) error {
+		workDir, err := os.MkdirTemp("", "report-converter-selftest-*")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(workDir)
+
+		input := filepath.Join(workDir, "selftest.txt")
+		if err := os.WriteFile(input, []byte("report-converter self-test\n"), 0o600); err != nil {
+			return err
+		}
+		if _, err := converter.ConvertToPDF(ctx, input, workDir); err != nil {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,47 @@
+package health
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/IlhamSetiaji/report-converter/storage"
+)
+
+func TestStorageCheckNeedsWritableStorage(t *testing.T) {
+	ctx := context.Background()
+
+	root := t.TempDir()
+	if err := Storage(storage.NewLocalStorage(root))(ctx); err != nil {
+		t.Fatalf("writable storage: %v", err)
+	}
+	if left, _ := os.ReadDir(filepath.Join(root, ".health")); len(left) != 0 {
+		t.Errorf("probe left %d objects behind", len(left))
+	}
+
+	// A file where the probe directory belongs makes every write fail,
+	// even for root.
+	blocked := t.TempDir()
+	if err := os.WriteFile(filepath.Join(blocked, ".health"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Storage(storage.NewLocalStorage(blocked))(ctx); err == nil {
+		t.Error("storage that cannot be written to passed the check")
+	}
+}
+
+func TestStorageCheckFailsOnReadOnlyDirectory(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root ignores directory permissions")
+	}
+	root := t.TempDir()
+	if err := os.Chmod(root, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(root, 0o755) })
+
+	if err := Storage(storage.NewLocalStorage(root))(context.Background()); err == nil {
+		t.Error("read-only storage passed the check")
+	}
+}
@@ -0,0 +1,129 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrPending is reported by a periodic check that has not completed yet.
+var ErrPending = errors.New("health: check has not run yet")
+
+// ErrDraining is reported while the server shuts down, so load balancers stop
+// sending new work before in-flight requests are drained.
+var ErrDraining = errors.New("health: server is shutting down")
+
+// Check reports whether a dependency is usable.
+type Check func(ctx context.Context) error
+
+// Result is the outcome of one check.
+type Result struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+}
+
+// Checker runs the readiness checks of the service.
+type Checker struct {
+	timeout  time.Duration
+	mu       sync.Mutex
+	checks   map[string]Check
+	draining atomic.Bool
+}
+
+// NewChecker returns a checker that gives each check at most timeout.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout, checks: map[string]Check{}}
+}
+
+// Register adds a named check.
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+// Drain makes every following readiness check fail with ErrDraining.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Run runs all checks concurrently and returns their results sorted by name,
+// and whether all of them passed.
+func (c *Checker) Run(ctx context.Context) ([]Result, bool) {
+	c.mu.Lock()
+	checks := make(map[string]Check, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.Unlock()
+
+	results := make([]Result, 0, len(checks)+1)
+	if c.draining.Load() {
+		results = append(results, Result{Name: "shutdown", Err: ErrDraining})
+	}
+
+	var wg sync.WaitGroup
+	resultCh := make(chan Result, len(checks))
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			started := time.Now()
+			err := check(checkCtx)
+			resultCh <- Result{Name: name, Err: err, Duration: time.Since(started)}
+		}(name, check)
+	}
+	wg.Wait()
+	close(resultCh)
+
+	ok := !c.draining.Load()
+	for result := range resultCh {
+		if result.Err != nil {
+			ok = false
+		}
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results, ok
+}
+
+// Periodic runs check in the background every interval until ctx is done and
+// returns a check reporting the latest outcome. It suits checks too slow to
+// run on every probe, such as a LibreOffice conversion.
+func Periodic(ctx context.Context, check Check, interval time.Duration, timeout time.Duration) Check {
+	var last atomic.Pointer[error]
+	pending := ErrPending
+	last.Store(&pending)
+
+	run := func() {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		err := check(checkCtx)
+		last.Store(&err)
+	}
+
+	go func() {
+		run()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				run()
+			}
+		}
+	}()
+
+	return func(context.Context) error {
+		return *last.Load()
+	}
+}
@@ -1,23 +1,104 @@
 package logger
 
-import "github.com/sirupsen/logrus"
+import (
+	"context"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Fields carried on a context and added to every line logged for it.
+const (
+	FieldRequestID  = "request_id"
+	FieldTenant     = "tenant"
+	FieldTemplateID = "template_id"
+	FieldJobID      = "job_id"
+)
 
 type Logger interface {
 	GetLogger() *logrus.Logger
+	// WithContext returns an entry carrying the fields bound to ctx and the
+	// trace it belongs to. Use it for everything logged on behalf of a request
+	// or job.
+	WithContext(ctx context.Context) *logrus.Entry
 }
 
 func (l *logger) GetLogger() *logrus.Logger {
 	return l.log
 }
 
+func (l *logger) WithContext(ctx context.Context) *logrus.Entry {
+	return entry(l.log, ctx)
+}
+
 type logger struct {
 	log *logrus.Logger
 }
 
-func NewLogger() Logger {
+// std is the logger behind FromContext, for packages that are not handed a
+// Logger. NewLogger replaces it, so both share one configuration.
+var std = func() *logrus.Logger {
 	l := logrus.New()
 	l.SetFormatter(&logrus.JSONFormatter{})
-	l.SetLevel(logrus.DebugLevel)
+	return l
+}()
 
+// NewLogger builds the JSON logger at the level set by log.level, which
+// defaults to info.
+func NewLogger(conf *config.Config) Logger {
+	l := logrus.New()
+	l.SetFormatter(&logrus.JSONFormatter{})
+	l.SetLevel(logrus.InfoLevel)
+	if conf.Log != nil && conf.Log.Level != "" {
+		level, err := logrus.ParseLevel(conf.Log.Level)
+		if err != nil {
+			l.WithError(err).Warn("Invalid log level, using info")
+		} else {
+			l.SetLevel(level)
+		}
+	}
+
+	std = l
 	return &logger{log: l}
 }
+
+// FromContext is WithContext on the process wide logger.
+func FromContext(ctx context.Context) *logrus.Entry {
+	return entry(std, ctx)
+}
+
+type fieldsKey struct{}
+
+// WithFields returns a copy of ctx whose log lines also carry fields.
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	merged := logrus.Fields{}
+	for k, v := range fieldsFrom(ctx) {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// WithField returns a copy of ctx whose log lines also carry key.
+func WithField(ctx context.Context, key string, value interface{}) context.Context {
+	return WithFields(ctx, logrus.Fields{key: value})
+}
+
+func fieldsFrom(ctx context.Context) logrus.Fields {
+	fields, _ := ctx.Value(fieldsKey{}).(logrus.Fields)
+	return fields
+}
+
+func entry(l *logrus.Logger, ctx context.Context) *logrus.Entry {
+	e := l.WithContext(ctx).WithFields(fieldsFrom(ctx))
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		e = e.WithFields(logrus.Fields{
+			"trace_id": span.TraceID().String(),
+			"span_id":  span.SpanID().String(),
+		})
+	}
+	return e
+}
@@ -1,21 +1,55 @@
 package main
 
 import (
+	"context"
+
 	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/IlhamSetiaji/report-converter/converter"
 	"github.com/IlhamSetiaji/report-converter/database"
 	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/migration"
 	"github.com/IlhamSetiaji/report-converter/server"
+	"github.com/IlhamSetiaji/report-converter/signer"
+	"github.com/IlhamSetiaji/report-converter/storage"
+	"github.com/IlhamSetiaji/report-converter/tracing"
 	"github.com/IlhamSetiaji/report-converter/validator"
 )
 
 func main() {
-	// Initialize the application components (config, logger, database, server)
+	// Initialize the application components (config, logger, database, storage, server)
 	config := config.GetConfig()
-	logger := logger.NewLogger()
+	logger := logger.NewLogger(config)
+	converter.Configure(config)
+	shutdownTracing, err := tracing.Setup(config)
+	if err != nil {
+		logger.GetLogger().WithError(err).Fatal("Failed to set up tracing")
+	}
+	defer shutdownTracing(context.Background())
 	db := database.NewPostgresDatabase(config)
+	if config.Db.CheckMigrations {
+		checkSchema(db, logger)
+	}
+	storage := storage.NewStorage(config)
+	signer := signer.NewHMACSigner(config)
 	validator := validator.NewValidatorV10(config)
-	server := server.NewGinServer(db, *config, logger, validator)
+	server := server.NewGinServer(db, storage, signer, *config, logger, validator)
 
-	// Start the server
+	// Start the server; it returns once it has shut down
 	server.Start()
+
+	if err := db.Close(); err != nil {
+		logger.GetLogger().WithError(err).Error("Failed to close database")
+	}
+}
+
+// checkSchema stops the server from starting against a database that is
+// missing migrations this build depends on.
+func checkSchema(db database.Database, logger logger.Logger) {
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		logger.GetLogger().WithError(err).Fatal("Failed to load migrations")
+	}
+	if err := migrator.Check(context.Background()); err != nil {
+		logger.GetLogger().WithError(err).Fatal("Database schema is not up to date, run cmd/migration up")
+	}
 }
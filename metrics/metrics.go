@@ -0,0 +1,63 @@
+// Package metrics holds the Prometheus collectors of the service. They are
+// registered with the default registry, which /metrics exposes.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "report_converter"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	RenderDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "render_duration_seconds",
+		Help:      "Time to produce a document, conversion included, by template type and engine.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60},
+	}, []string{"template_type", "engine"})
+
+	ConversionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "conversion_duration_seconds",
+		Help:      "Time LibreOffice spent converting a document, by outcome.",
+		Buckets:   []float64{0.25, 0.5, 1, 2.5, 5, 10, 20, 30},
+	}, []string{"engine", "outcome"})
+
+	ConversionFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "conversion_failures_total",
+		Help:      "Failed LibreOffice conversions by reason: error, timeout or unavailable.",
+	}, []string{"engine", "reason"})
+
+	ConversionQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "conversion_queue_depth",
+		Help:      "Conversions waiting for a free LibreOffice slot.",
+	})
+
+	ActiveConversions = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_conversions",
+		Help:      "LibreOffice conversions currently running.",
+	})
+
+	StorageErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_errors_total",
+		Help:      "Failed storage operations by driver and operation. Missing objects are not counted.",
+	}, []string{"driver", "operation"})
+)
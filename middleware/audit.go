@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"github.com/IlhamSetiaji/report-converter/audit"
+	"github.com/IlhamSetiaji/report-converter/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// RecordActor binds the request context to the caller and the address it
+// came from, which the use case layer writes to the audit log.
+func RecordActor() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		actor := audit.Actor{
+			Subject:   "anonymous",
+			IP:        ctx.ClientIP(),
+			UserAgent: ctx.Request.UserAgent(),
+		}
+		if principal := auth.GetPrincipal(ctx); principal != nil {
+			actor.Subject = principal.Subject
+		}
+
+		ctx.Request = ctx.Request.WithContext(audit.WithActor(ctx.Request.Context(), actor))
+		ctx.Next()
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/IlhamSetiaji/report-converter/apperror"
+	"github.com/IlhamSetiaji/report-converter/auth"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/gin-gonic/gin"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+var (
+	errMissingCredentials = errors.New("missing bearer token or API key")
+	// errInvalidToken stands in for the verifier's error, which is only
+	// logged.
+	errInvalidToken = errors.New("invalid bearer token")
+)
+
+// Authenticate rejects requests that carry neither a valid bearer token nor
+// a valid X-API-Key and exposes the caller to handlers through
+// auth.GetPrincipal. An X-API-Key header takes precedence over a token.
+func Authenticate(tokens auth.TokenVerifier, apiKeys auth.ApiKeyVerifier, log logger.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if key := ctx.GetHeader(apiKeyHeader); key != "" {
+			principal, err := apiKeys.VerifyApiKey(ctx.Request.Context(), key)
+			if err != nil {
+				if !errors.Is(err, auth.ErrInvalidApiKey) {
+					ctx.Error(apperror.Wrap(err, "Failed to verify API key"))
+					ctx.Abort()
+					return
+				}
+				ctx.Error(apperror.WrapAs(err, apperror.CodeUnauthorized, "Unauthorized"))
+				ctx.Abort()
+				return
+			}
+
+			auth.SetPrincipal(ctx, principal)
+			ctx.Next()
+			return
+		}
+
+		token, ok := bearerToken(ctx.GetHeader("Authorization"))
+		if !ok {
+			ctx.Header("WWW-Authenticate", `Bearer`)
+			ctx.Error(apperror.WrapAs(errMissingCredentials, apperror.CodeUnauthorized, "Unauthorized"))
+			ctx.Abort()
+			return
+		}
+
+		principal, err := tokens.Verify(token)
+		if err != nil {
+			log.WithContext(ctx.Request.Context()).WithError(err).Warn("Rejected bearer token")
+			ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			ctx.Error(apperror.WrapAs(errInvalidToken, apperror.CodeUnauthorized, "Unauthorized"))
+			ctx.Abort()
+			return
+		}
+
+		auth.SetPrincipal(ctx, principal)
+		ctx.Next()
+	}
+}
+
+func bearerToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/IlhamSetiaji/report-converter/apperror"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/utils"
+	"github.com/IlhamSetiaji/report-converter/validator"
+	"github.com/gin-gonic/gin"
+)
+
+// HandleErrors writes the response of a request that failed with ctx.Error.
+// The last error wins; its apperror code decides the status, and errors
+// without one are internal. Failures are logged here, once, with the request
+// fields. Server errors answer with a fixed message, never the cause.
+// Validation failures list their fields, translated to the caller's
+// Accept-Language.
+func HandleErrors(log logger.Logger, validate validator.Validator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+			return
+		}
+
+		err := ctx.Errors.Last().Err
+		appErr := apperror.As(err)
+		status := apperror.Status(appErr.Code)
+
+		entry := log.WithContext(ctx.Request.Context()).WithError(err).WithField("error_code", appErr.Code)
+		if status >= http.StatusInternalServerError {
+			entry.Error(appErr.Message)
+		} else {
+			entry.Warn(appErr.Message)
+		}
+
+		message, details := err.Error(), appErr.Details
+		if status >= http.StatusInternalServerError {
+			// The cause can name files, hosts or queries. Callers get the
+			// stable code and quote the request ID; the cause is logged above.
+			message, details = appErr.Message, nil
+			if appErr.Code == apperror.CodeInternal {
+				message = "Internal server error"
+			}
+		} else if fieldErrors := validate.Translate(err, ctx.GetHeader("Accept-Language")); fieldErrors != nil {
+			messages := make([]string, len(fieldErrors))
+			for i, fieldError := range fieldErrors {
+				messages[i] = fieldError.Message
+			}
+			message, details = strings.Join(messages, "; "), fieldErrors
+		}
+
+		utils.FailureResponse(ctx, status, appErr.Message, message, string(appErr.Code), details)
+	}
+}
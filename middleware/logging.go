@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/apperror"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the correlation ID of a request in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern limits what a client supplied ID may contain, so it is
+// safe to echo back and to write into logs.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID accepts the caller's X-Request-ID, or creates one, echoes it on
+// the response and binds it to the request context for logging.
+func RequestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = uuid.NewString()
+		}
+
+		ctx.Header(RequestIDHeader, id)
+		ctx.Request = ctx.Request.WithContext(logger.WithField(ctx.Request.Context(), logger.FieldRequestID, id))
+		ctx.Next()
+	}
+}
+
+// LogParam binds a path parameter to the request context under field, so
+// every line logged for the request names the resource it is about.
+func LogParam(param string, field string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if value := ctx.Param(param); value != "" {
+			ctx.Request = ctx.Request.WithContext(logger.WithField(ctx.Request.Context(), field, value))
+		}
+		ctx.Next()
+	}
+}
+
+// AccessLog writes one line per request once it has been served.
+func AccessLog(log logger.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		started := time.Now()
+		ctx.Next()
+
+		entry := log.WithContext(ctx.Request.Context()).WithFields(map[string]interface{}{
+			"method":     ctx.Request.Method,
+			"path":       ctx.Request.URL.Path,
+			"route":      ctx.FullPath(),
+			"status":     ctx.Writer.Status(),
+			"latency_ms": time.Since(started).Milliseconds(),
+			"client_ip":  ctx.ClientIP(),
+			"bytes":      ctx.Writer.Size(),
+		})
+		if len(ctx.Errors) > 0 {
+			entry = entry.WithField("errors", ctx.Errors.String())
+		}
+		switch status := ctx.Writer.Status(); {
+		case status >= http.StatusInternalServerError:
+			entry.Error("Request served")
+		case status >= http.StatusBadRequest:
+			entry.Warn("Request served")
+		default:
+			entry.Info("Request served")
+		}
+	}
+}
+
+// Recover turns a panic into a 500 response and logs it with its stack.
+func Recover(log logger.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				log.WithContext(ctx.Request.Context()).
+					WithField("panic", recovered).
+					WithField("stack", string(debug.Stack())).
+					Error("Recovered from panic")
+				if !ctx.Writer.Written() {
+					utils.FailureResponse(ctx, http.StatusInternalServerError, "Internal server error",
+						"Internal server error", string(apperror.CodeInternal), nil)
+				}
+				ctx.Abort()
+			}
+		}()
+		ctx.Next()
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics counts requests and observes their latency per route. Routes are
+// labelled with their pattern rather than the raw path so template IDs do not
+// explode the label cardinality.
+func Metrics() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		started := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := ctx.Request.Method
+		metrics.HTTPRequests.WithLabelValues(method, route, strconv.Itoa(ctx.Writer.Status())).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(started).Seconds())
+	}
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/apperror"
+	"github.com/IlhamSetiaji/report-converter/auth"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/ratelimit"
+	"github.com/IlhamSetiaji/report-converter/tenant"
+	"github.com/IlhamSetiaji/report-converter/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+var errRateLimited = errors.New("rate limit exceeded")
+
+// QuotaConsumer counts a render against the quotas of the tenant of ctx and
+// takes it back when the render fails.
+type QuotaConsumer interface {
+	ConsumeRender(ctx context.Context) (*usecase.QuotaResult, error)
+	RefundRender(ctx context.Context, result *usecase.QuotaResult) error
+}
+
+// RateLimit throttles each client with its own token bucket. API keys get a
+// bucket each; every other caller shares the bucket of its tenant.
+func RateLimit(limiter ratelimit.Limiter, log logger.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		result, err := limiter.Allow(ctx.Request.Context(), rateLimitKey(ctx))
+		if err != nil {
+			// Failing open keeps renders going when the shared backend is
+			// down; the quota still bounds the damage.
+			log.WithContext(ctx.Request.Context()).WithError(err).Error("Failed to check rate limit")
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		ctx.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Header("X-RateLimit-Reset", strconv.FormatInt(result.Reset.Unix(), 10))
+		if !result.Allowed {
+			ctx.Header("Retry-After", retryAfter(result.RetryAfter))
+			ctx.Error(apperror.WrapAs(errRateLimited, apperror.CodeRateLimited, "Too many requests"))
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// RenderQuota rejects renders once the tenant's daily or monthly quota is
+// used up. The quota is taken up front so concurrent renders cannot overrun
+// it, and refunded when the render does not succeed.
+func RenderQuota(quota QuotaConsumer, log logger.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		result, err := quota.ConsumeRender(ctx.Request.Context())
+		if err != nil {
+			ctx.Error(apperror.Wrap(err, "Failed to check render quota"))
+			ctx.Abort()
+			return
+		}
+		if result.Limit == 0 {
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("X-Quota-Period", string(result.Period))
+		ctx.Header("X-Quota-Limit", strconv.Itoa(result.Limit))
+		ctx.Header("X-Quota-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Header("X-Quota-Reset", strconv.FormatInt(result.Reset.Unix(), 10))
+		if !result.Allowed {
+			ctx.Header("Retry-After", retryAfter(time.Until(result.Reset)))
+			ctx.Error(apperror.WrapAs(fmt.Errorf("%s render quota exceeded", result.Period), apperror.CodeQuotaExceeded, "Quota exceeded"))
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+
+		if len(ctx.Errors) == 0 && ctx.Writer.Status() >= 200 && ctx.Writer.Status() < 300 {
+			return
+		}
+		// The client may be gone, but the refund is still owed.
+		refundCtx := context.WithoutCancel(ctx.Request.Context())
+		if err := quota.RefundRender(refundCtx, result); err != nil {
+			log.WithContext(refundCtx).WithError(err).Error("Failed to refund render quota")
+		}
+	}
+}
+
+func rateLimitKey(ctx *gin.Context) string {
+	if principal := auth.GetPrincipal(ctx); principal != nil && principal.Method == "api_key" {
+		return principal.Subject
+	}
+	return "tenant:" + tenant.FromContext(ctx.Request.Context())
+}
+
+// retryAfter formats d as whole seconds, rounded up so clients do not retry
+// a moment too early.
+func retryAfter(d time.Duration) string {
+	return strconv.Itoa(int(math.Max(1, math.Ceil(d.Seconds()))))
+}
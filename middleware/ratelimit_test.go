@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// countingQuota allows every render and counts charges and refunds.
+type countingQuota struct {
+	charged, refunded int
+}
+
+func (q *countingQuota) ConsumeRender(ctx context.Context) (*usecase.QuotaResult, error) {
+	q.charged++
+	return &usecase.QuotaResult{Allowed: true, Limit: 10, Remaining: 10 - q.charged}, nil
+}
+
+func (q *countingQuota) RefundRender(ctx context.Context, result *usecase.QuotaResult) error {
+	q.refunded++
+	return nil
+}
+
+func TestRenderQuotaRefundsFailedRenders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := logger.NewLogger(&config.Config{Server: &config.Server{Name: "report-converter"}})
+	quota := &countingQuota{}
+
+	app := gin.New()
+	app.POST("/ok", RenderQuota(quota, log), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	app.POST("/fail", RenderQuota(quota, log), func(c *gin.Context) {
+		c.Error(errors.New("conversion failed"))
+	})
+	app.POST("/bad", RenderQuota(quota, log), func(c *gin.Context) {
+		c.Status(http.StatusBadRequest)
+	})
+
+	for _, path := range []string{"/ok", "/fail", "/bad"} {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+	}
+	if quota.charged != 3 || quota.refunded != 2 {
+		t.Errorf("charged %d and refunded %d renders, want 3 and 2", quota.charged, quota.refunded)
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/IlhamSetiaji/report-converter/apperror"
+	"github.com/IlhamSetiaji/report-converter/auth"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/rbac"
+	"github.com/gin-gonic/gin"
+)
+
+const permissionsKey = "rbac.permissions"
+
+// PermissionResolver works out what a caller may do in the tenant of ctx.
+type PermissionResolver interface {
+	ResolvePermissions(ctx context.Context, principal *auth.Principal) (rbac.PermissionSet, error)
+}
+
+// RequirePermission rejects callers that lack permission with 403. The
+// caller's permissions are resolved once per request and then reused.
+func RequirePermission(resolver PermissionResolver, permission rbac.Permission, log logger.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		permissions, err := permissionsOf(ctx, resolver)
+		if err != nil {
+			ctx.Error(apperror.Wrap(err, "Failed to resolve permissions"))
+			ctx.Abort()
+			return
+		}
+
+		if !permissions.Has(permission) {
+			ctx.Error(apperror.WrapAs(fmt.Errorf("missing permission %s", permission), apperror.CodeForbidden, "Forbidden"))
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func permissionsOf(ctx *gin.Context, resolver PermissionResolver) (rbac.PermissionSet, error) {
+	if value, ok := ctx.Get(permissionsKey); ok {
+		return value.(rbac.PermissionSet), nil
+	}
+
+	permissions, err := resolver.ResolvePermissions(ctx.Request.Context(), auth.GetPrincipal(ctx))
+	if err != nil {
+		return nil, err
+	}
+	ctx.Set(permissionsKey, permissions)
+	return permissions, nil
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/IlhamSetiaji/report-converter/apperror"
+	"github.com/IlhamSetiaji/report-converter/auth"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// ResolveTenant binds the request context to the caller's tenant, which
+// repositories and storage keys are scoped by. Anonymous requests, which
+// only reach it when authentication is disabled, act for tenant.Default.
+func ResolveTenant() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		tenantID := tenant.Default
+		if principal := auth.GetPrincipal(ctx); principal != nil {
+			tenantID = principal.TenantID
+		}
+
+		if err := tenant.Validate(tenantID); err != nil {
+			ctx.Error(apperror.WrapAs(err, apperror.CodeForbidden, "Caller is not bound to a valid tenant"))
+			ctx.Abort()
+			return
+		}
+
+		reqCtx := logger.WithField(tenant.WithID(ctx.Request.Context(), tenantID), logger.FieldTenant, tenantID)
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		ctx.Next()
+	}
+}
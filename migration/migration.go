@@ -0,0 +1,144 @@
+// Package migration manages the database schema with versioned SQL files.
+//
+// Each migration is a pair of files in migrations/ named
+// <version>_<name>.up.sql and <version>_<name>.down.sql, where the version
+// is the UTC time the migration was created as YYYYMMDDHHMMSS. The files are
+// embedded into the binary. Applied versions are recorded in the
+// schema_migrations table.
+package migration
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var embedded embed.FS
+
+// noTransaction, as the first line of a file, runs it outside a transaction,
+// which statements such as CREATE INDEX CONCURRENTLY require. A failure then
+// leaves the version dirty until it is repaired by hand and forced.
+const noTransaction = "-- migrate:no-transaction"
+
+const versionLayout = "20060102150405"
+
+var (
+	fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+	namePattern     = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Transactional reports whether the SQL of a direction runs in a transaction.
+func Transactional(sql string) bool {
+	firstLine, _, _ := strings.Cut(sql, "\n")
+	return strings.TrimSpace(firstLine) != noTransaction
+}
+
+// Migrations returns the migrations embedded into the binary.
+func Migrations() ([]Migration, error) {
+	sub, err := fs.Sub(embedded, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return Load(sub)
+}
+
+// Load reads the migrations in the root of fsys, ordered by version. Every
+// version needs both an up and a down file.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	found := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration file %s is not named <version>_<name>.<up|down>.sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration file %s: %w", entry.Name(), err)
+		}
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files named %s and %s", version, migration.Name, match[2])
+		}
+		found[strconv.FormatInt(version, 10)+"."+match[3]] = true
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		version := strconv.FormatInt(migration.Version, 10)
+		if !found[version+".up"] || !found[version+".down"] {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Create writes an empty pair of migration files for name into dir and
+// returns their paths. The migration only takes effect once the binary is
+// rebuilt with it embedded.
+func Create(dir string, name string, now time.Time) (string, string, error) {
+	name = strings.Trim(namePattern.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", fmt.Errorf("migration name must contain letters or digits")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", err
+	}
+
+	base := filepath.Join(dir, now.UTC().Format(versionLayout)+"_"+name)
+	upPath, downPath := base+".up.sql", base+".down.sql"
+	for path, direction := range map[string]string{upPath: "apply", downPath: "revert"} {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return "", "", err
+		}
+		_, err = fmt.Fprintf(file, "-- SQL to %s %s.\n", direction, name)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return upPath, downPath, nil
+}
@@ -0,0 +1,7 @@
+-- The baseline adopts databases AutoMigrate created, which hold production
+-- data, so it is never reverted. Drop the tables by hand to start over.
+DO $$
+BEGIN
+	RAISE EXCEPTION 'the initial schema migration cannot be reverted';
+END;
+$$;
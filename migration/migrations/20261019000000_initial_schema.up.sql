@@ -0,0 +1,184 @@
+-- The schema previously created by AutoMigrate. Everything is guarded with
+-- IF NOT EXISTS so databases set up that way can adopt migrations as is.
+-- Tables such a database already has may predate some of their columns, so
+-- every column added after a table first appeared is added again here.
+
+CREATE TABLE IF NOT EXISTS templates (
+	id uuid NOT NULL,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	tenant_id varchar(64) NOT NULL DEFAULT 'default',
+	name varchar(255) NOT NULL,
+	template_type varchar(255) NOT NULL,
+	path text NOT NULL,
+	description text,
+	category varchar(100),
+	tags jsonb NOT NULL DEFAULT '[]',
+	owner varchar(255),
+	sample_data jsonb,
+	output_format varchar(20) NOT NULL DEFAULT 'pdf',
+	current_version bigint NOT NULL DEFAULT 1,
+	status varchar(20) NOT NULL DEFAULT 'published',
+	approved_by varchar(255),
+	approved_at timestamptz,
+	content text,
+	search_vector tsvector GENERATED ALWAYS AS (to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(content, ''))) STORED,
+	PRIMARY KEY (id)
+);
+ALTER TABLE templates
+	ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default',
+	ADD COLUMN IF NOT EXISTS description text,
+	ADD COLUMN IF NOT EXISTS category varchar(100),
+	ADD COLUMN IF NOT EXISTS tags jsonb NOT NULL DEFAULT '[]',
+	ADD COLUMN IF NOT EXISTS owner varchar(255),
+	ADD COLUMN IF NOT EXISTS sample_data jsonb,
+	ADD COLUMN IF NOT EXISTS output_format varchar(20) NOT NULL DEFAULT 'pdf',
+	ADD COLUMN IF NOT EXISTS current_version bigint NOT NULL DEFAULT 1,
+	ADD COLUMN IF NOT EXISTS status varchar(20) NOT NULL DEFAULT 'published',
+	ADD COLUMN IF NOT EXISTS approved_by varchar(255),
+	ADD COLUMN IF NOT EXISTS approved_at timestamptz,
+	ADD COLUMN IF NOT EXISTS content text;
+ALTER TABLE templates
+	ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(content, ''))) STORED;
+CREATE INDEX IF NOT EXISTS idx_templates_deleted_at ON templates (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_templates_tenant_id ON templates (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_templates_category ON templates (category);
+CREATE INDEX IF NOT EXISTS idx_templates_owner ON templates (owner);
+CREATE INDEX IF NOT EXISTS idx_templates_status ON templates (status);
+CREATE INDEX IF NOT EXISTS idx_templates_tags ON templates USING gin (tags);
+CREATE INDEX IF NOT EXISTS idx_templates_search_vector ON templates USING gin (search_vector);
+
+CREATE TABLE IF NOT EXISTS template_versions (
+	id uuid NOT NULL,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	tenant_id varchar(64) NOT NULL DEFAULT 'default',
+	template_id uuid NOT NULL,
+	version bigint NOT NULL,
+	path text NOT NULL,
+	checksum varchar(64) NOT NULL,
+	PRIMARY KEY (id)
+);
+ALTER TABLE template_versions ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+CREATE INDEX IF NOT EXISTS idx_template_versions_deleted_at ON template_versions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_template_versions_tenant_id ON template_versions (tenant_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_template_versions_template_id_version ON template_versions (template_id, version);
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_templates_versions') THEN
+		ALTER TABLE template_versions
+			ADD CONSTRAINT fk_templates_versions FOREIGN KEY (template_id) REFERENCES templates (id);
+	END IF;
+END;
+$$;
+
+CREATE TABLE IF NOT EXISTS download_nonces (
+	nonce varchar(64) NOT NULL,
+	expires_at timestamptz NOT NULL,
+	consumed_at timestamptz NOT NULL,
+	PRIMARY KEY (nonce)
+);
+CREATE INDEX IF NOT EXISTS idx_download_nonces_expires_at ON download_nonces (expires_at);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+	id uuid NOT NULL,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	tenant_id varchar(64) NOT NULL DEFAULT 'default',
+	name varchar(255) NOT NULL,
+	prefix varchar(16) NOT NULL,
+	key_hash varchar(64) NOT NULL,
+	scopes jsonb NOT NULL DEFAULT '[]',
+	created_by varchar(255),
+	expires_at timestamptz,
+	last_used_at timestamptz,
+	revoked_at timestamptz,
+	PRIMARY KEY (id)
+);
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+CREATE INDEX IF NOT EXISTS idx_api_keys_deleted_at ON api_keys (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_api_keys_tenant_id ON api_keys (tenant_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys (key_hash);
+
+CREATE TABLE IF NOT EXISTS role_assignments (
+	id uuid NOT NULL,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	tenant_id varchar(64) NOT NULL,
+	subject varchar(255) NOT NULL,
+	role varchar(32) NOT NULL,
+	granted_by varchar(255),
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_role_assignments_deleted_at ON role_assignments (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_role_assignments_tenant_subject_role ON role_assignments (tenant_id, subject, role);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+	id uuid NOT NULL,
+	tenant_id varchar(64) NOT NULL,
+	actor varchar(255) NOT NULL,
+	action varchar(64) NOT NULL,
+	template_id uuid,
+	template_version bigint,
+	ip varchar(64),
+	user_agent text,
+	data_hash varchar(64),
+	details jsonb,
+	created_at timestamptz NOT NULL,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_id_created_at ON audit_logs (tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor ON audit_logs (actor);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs (action);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_template_id ON audit_logs (template_id);
+
+-- The audit trail is append-only; enforce it in the database as well.
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+CREATE TRIGGER audit_logs_append_only
+	BEFORE UPDATE OR DELETE ON audit_logs
+	FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
+
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+	"key" varchar(255) NOT NULL,
+	tokens decimal NOT NULL,
+	allowed boolean NOT NULL,
+	updated_at timestamptz NOT NULL,
+	PRIMARY KEY ("key")
+);
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated_at ON rate_limit_buckets (updated_at);
+
+CREATE TABLE IF NOT EXISTS render_quota_usages (
+	tenant_id varchar(64) NOT NULL,
+	period varchar(8) NOT NULL,
+	period_start date NOT NULL,
+	count bigint NOT NULL,
+	PRIMARY KEY (tenant_id, period, period_start)
+);
+
+CREATE TABLE IF NOT EXISTS usage_records (
+	id uuid NOT NULL,
+	tenant_id varchar(64) NOT NULL,
+	template_id uuid NOT NULL,
+	template_version bigint NOT NULL,
+	engine varchar(64) NOT NULL,
+	output_format varchar(16) NOT NULL,
+	preview boolean NOT NULL DEFAULT false,
+	page_count bigint NOT NULL,
+	output_bytes bigint NOT NULL,
+	cpu_millis bigint NOT NULL,
+	wall_millis bigint NOT NULL,
+	created_at timestamptz NOT NULL,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_usage_records_tenant_id_created_at ON usage_records (tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_usage_records_template_id ON usage_records (template_id);
@@ -0,0 +1,5 @@
+-- Generate renders the published version, which stays put while a new
+-- working copy is reviewed. Templates published so far publish their
+-- current version.
+ALTER TABLE templates ADD COLUMN published_version bigint NOT NULL DEFAULT 0;
+UPDATE templates SET published_version = current_version WHERE status = 'published';
@@ -0,0 +1,7 @@
+-- Only versions that were approved can be generated from, pinned or not.
+-- The versions published so far are the published templates' current ones.
+ALTER TABLE template_versions ADD COLUMN published_at timestamptz;
+UPDATE template_versions v
+SET published_at = coalesce(t.approved_at, t.updated_at, now())
+FROM templates t
+WHERE t.id = v.template_id AND t.published_version = v.version;
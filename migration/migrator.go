@@ -0,0 +1,314 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/database"
+	"gorm.io/gorm"
+)
+
+const table = "schema_migrations"
+
+// lockKey identifies the advisory lock held while the schema changes, so two
+// deployments cannot migrate the same database at once.
+const lockKey int64 = 0x7265706f7274
+
+var (
+	// ErrDirty is returned while a migration that ran outside a transaction
+	// is recorded as failed half way. Repair the schema by hand, then force
+	// the version it is in.
+	ErrDirty = errors.New("database schema is dirty")
+	// ErrOutdated is returned by Check when migrations are pending.
+	ErrOutdated = errors.New("database schema is out of date")
+)
+
+// Status is the state of one migration version.
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	Dirty     bool
+	AppliedAt *time.Time
+	// Missing marks a version recorded in the database that this binary has
+	// no files for, usually because a newer release migrated the database.
+	Missing bool
+}
+
+// schemaMigration is a row of the schema_migrations table.
+type schemaMigration struct {
+	Version   int64
+	Name      string
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator returns a migrator for the migrations embedded into the binary.
+func NewMigrator(db database.Database) (*Migrator, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db.GetDb(), migrations: migrations}, nil
+}
+
+// Up applies every pending migration in version order and returns them.
+// Versions older than the newest applied one, as happens when branches are
+// merged, are applied as well.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		if err := checkClean(applied); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(conn, migration); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the n most recently applied migrations, newest first, and
+// returns them.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("number of migrations to revert must be positive")
+	}
+
+	var done []Migration
+	err := m.withLock(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		if err := checkClean(applied); err != nil {
+			return err
+		}
+
+		var rows []schemaMigration
+		if err := conn.Table(table).Order("version DESC").Limit(n).Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			migration, ok := m.find(row.Version)
+			if !ok {
+				return fmt.Errorf("migration %d_%s has no files in this binary", row.Version, row.Name)
+			}
+			if err := m.revert(conn, migration); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status lists every known version, applied or not, in version order.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	db := m.db.WithContext(ctx)
+	applied := map[int64]schemaMigration{}
+	if db.Migrator().HasTable(table) {
+		var err error
+		if applied, err = m.applied(db); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			status.Applied, status.Dirty, status.AppliedAt = true, row.Dirty, &row.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range applied {
+		statuses = append(statuses, Status{
+			Version:   row.Version,
+			Name:      row.Name,
+			Applied:   true,
+			Dirty:     row.Dirty,
+			AppliedAt: &row.AppliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Force records the schema as being exactly at version without running any
+// SQL: later versions are forgotten, earlier ones are marked applied and the
+// dirty flag is cleared. Version 0 forgets every migration.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	if _, ok := m.find(version); !ok && version != 0 {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	return m.withLock(ctx, func(conn *gorm.DB) error {
+		return conn.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("DELETE FROM "+table+" WHERE version > ?", version).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("UPDATE " + table + " SET dirty = false").Error; err != nil {
+				return err
+			}
+			for _, migration := range m.migrations {
+				if migration.Version > version {
+					break
+				}
+				err := tx.Exec(
+					"INSERT INTO "+table+" (version, name) VALUES (?, ?) ON CONFLICT (version) DO NOTHING",
+					migration.Version, migration.Name,
+				).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// Check fails with ErrOutdated when migrations are pending and with ErrDirty
+// when one failed half way. A database migrated by a newer release passes.
+func (m *Migrator) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	var pending []int64
+	for _, status := range statuses {
+		if status.Dirty {
+			return fmt.Errorf("%w: migration %d_%s failed", ErrDirty, status.Version, status.Name)
+		}
+		if !status.Applied {
+			pending = append(pending, status.Version)
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migration(s), the oldest is %d", ErrOutdated, len(pending), pending[0])
+	}
+	return nil
+}
+
+// withLock runs fn on a single connection holding the migration lock, after
+// making sure the schema_migrations table exists.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error; err != nil {
+			return err
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", lockKey)
+
+		err := conn.Exec(`CREATE TABLE IF NOT EXISTS ` + table + ` (
+			version bigint NOT NULL,
+			name varchar(255) NOT NULL,
+			dirty boolean NOT NULL DEFAULT false,
+			applied_at timestamptz NOT NULL DEFAULT now(),
+			PRIMARY KEY (version)
+		)`).Error
+		if err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+func (m *Migrator) applied(db *gorm.DB) (map[int64]schemaMigration, error) {
+	var rows []schemaMigration
+	if err := db.Table(table).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+func (m *Migrator) apply(conn *gorm.DB, migration Migration) error {
+	record := func(tx *gorm.DB) error {
+		return tx.Exec("INSERT INTO "+table+" (version, name) VALUES (?, ?)", migration.Version, migration.Name).Error
+	}
+	if Transactional(migration.Up) {
+		return conn.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return err
+			}
+			return record(tx)
+		})
+	}
+
+	err := conn.Exec("INSERT INTO "+table+" (version, name, dirty) VALUES (?, ?, true)", migration.Version, migration.Name).Error
+	if err != nil {
+		return err
+	}
+	if err := conn.Exec(migration.Up).Error; err != nil {
+		return err
+	}
+	return conn.Exec("UPDATE "+table+" SET dirty = false WHERE version = ?", migration.Version).Error
+}
+
+func (m *Migrator) revert(conn *gorm.DB, migration Migration) error {
+	forget := func(tx *gorm.DB) error {
+		return tx.Exec("DELETE FROM "+table+" WHERE version = ?", migration.Version).Error
+	}
+	if Transactional(migration.Down) {
+		return conn.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			return forget(tx)
+		})
+	}
+
+	if err := conn.Exec("UPDATE "+table+" SET dirty = true WHERE version = ?", migration.Version).Error; err != nil {
+		return err
+	}
+	if err := conn.Exec(migration.Down).Error; err != nil {
+		return err
+	}
+	return forget(conn)
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+func checkClean(applied map[int64]schemaMigration) error {
+	for _, row := range applied {
+		if row.Dirty {
+			return fmt.Errorf("%w: migration %d_%s failed, repair it and run force", ErrDirty, row.Version, row.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+//go:build ignore
+
+// gen_swaggerui vendors the Swagger UI files the docs page loads into
+// swagger-ui/. They are taken from the swagger-ui dist bundle published as
+// the Go module below, so the go command fetches it and verifies it against
+// the checksum database. Run it through go generate after changing the
+// module version and commit the result.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+const (
+	swaggerUIModule = "github.com/swaggo/files/v2"
+	// swaggerUIModuleVersion bundles swagger-ui 5.18.2.
+	swaggerUIModuleVersion = "v2.0.2"
+	swaggerUIModuleSum     = "h1:Bq4tgS/yxLB/3nwOMcul5oLEUKa877Ykgz3CJMVbQKU="
+)
+
+// files are the parts of the bundle docs.html needs, by their path in the
+// module, plus the module's licence.
+var files = map[string]string{
+	"dist/swagger-ui.css":       "swagger-ui.css",
+	"dist/swagger-ui-bundle.js": "swagger-ui-bundle.js",
+	"LICENSE":                   "LICENSE",
+}
+
+func main() {
+	out, err := exec.Command("go", "mod", "download", "-json", swaggerUIModule+"@"+swaggerUIModuleVersion).Output()
+	if err != nil {
+		log.Fatalf("download %s@%s: %v", swaggerUIModule, swaggerUIModuleVersion, err)
+	}
+	var module struct {
+		Dir   string
+		Sum   string
+		Error string
+	}
+	if err := json.Unmarshal(out, &module); err != nil {
+		log.Fatalf("parse go mod download output: %v", err)
+	}
+	if module.Error != "" {
+		log.Fatal(module.Error)
+	}
+	if module.Sum != swaggerUIModuleSum {
+		log.Fatalf("%s@%s has checksum %s, want %s", swaggerUIModule, swaggerUIModuleVersion, module.Sum, swaggerUIModuleSum)
+	}
+
+	for src, dst := range files {
+		data, err := os.ReadFile(filepath.Join(module.Dir, filepath.FromSlash(src)))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join("swagger-ui", dst), data, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+	fmt.Printf("Vendored Swagger UI from %s@%s into swagger-ui/\n", swaggerUIModule, swaggerUIModuleVersion)
+}
@@ -0,0 +1,97 @@
+// Package openapi serves the OpenAPI document of the service and checks it
+// against the routes actually registered, so generated clients stay accurate.
+package openapi
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+//go:embed docs.html
+var docsPage []byte
+
+// swaggerUI holds the vendored Swagger UI the docs page loads, so browsers
+// never run scripts from a CDN.
+//
+//go:generate go run gen_swaggerui.go
+//go:embed swagger-ui
+var swaggerUI embed.FS
+
+// Spec returns the OpenAPI 3 document.
+func Spec() []byte {
+	return spec
+}
+
+// ServeSpec writes the OpenAPI document.
+func ServeSpec(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+}
+
+// ServeDocs writes the interactive documentation page, which renders the
+// document served at /openapi.json.
+func ServeDocs(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+}
+
+// ServeDocsAsset writes a file of the vendored Swagger UI named by the name
+// path parameter.
+func ServeDocsAsset(ctx *gin.Context) {
+	ctx.FileFromFS("swagger-ui/"+ctx.Param("name"), http.FS(swaggerUI))
+}
+
+type document struct {
+	Paths map[string]map[string]operation `json:"paths"`
+}
+
+type operation struct {
+	// Optional marks routes that are only registered with some
+	// configurations, such as /metrics.
+	Optional bool `json:"x-optional"`
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// CheckRoutes compares the document with the registered routes and describes
+// every route missing from either side. An empty result means they match.
+func CheckRoutes(routes gin.RoutesInfo) ([]string, error) {
+	var doc document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("openapi: parse document: %w", err)
+	}
+
+	documented := map[string]bool{}
+	for path, operations := range doc.Paths {
+		ginPath := pathParam.ReplaceAllString(path, ":$1")
+		for method, op := range operations {
+			documented[strings.ToUpper(method)+" "+ginPath] = op.Optional
+		}
+	}
+
+	var problems []string
+	registered := map[string]bool{}
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if _, ok := documented[key]; !ok {
+			problems = append(problems, "route not documented: "+key)
+		}
+	}
+	for key, optional := range documented {
+		if !registered[key] && !optional {
+			problems = append(problems, "documented route not registered: "+key)
+		}
+	}
+
+	sort.Strings(problems)
+	return problems, nil
+}
@@ -0,0 +1,29 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDocsAssetsAreEmbedded(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := gin.New()
+	app.GET("/docs/assets/:name", ServeDocsAsset)
+
+	for _, name := range []string{"swagger-ui-bundle.js", "swagger-ui.css"} {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/assets/"+name, nil))
+		if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+			t.Errorf("GET /docs/assets/%s = %d with %d bytes, want 200 with a body", name, rec.Code, rec.Body.Len())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/assets/missing.js", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /docs/assets/missing.js = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// idleBucketTTL is how long an untouched bucket is kept. A bucket idle for
+// longer is full again anyway, so dropping it changes nothing.
+const idleBucketTTL = 10 * time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+type memoryLimiter struct {
+	rate  float64
+	burst int
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func newMemoryLimiter(rate float64, burst int) *memoryLimiter {
+	return &memoryLimiter{
+		rate:      rate,
+		burst:     burst,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *memoryLimiter) Allow(_ context.Context, key string) (*Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), updated: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return result(allowed, b.tokens, l.rate, l.burst, now), nil
+}
+
+func (l *memoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleBucketTTL {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) > idleBucketTTL {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
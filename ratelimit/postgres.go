@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/database"
+)
+
+// takeTokenSQL refills and takes from a bucket in a single statement, so
+// concurrent requests on different instances cannot both spend the last
+// token. Time is taken from the database to avoid clock skew between
+// instances.
+const takeTokenSQL = `
+INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+VALUES (@key, @burst::float8 - 1, true, clock_timestamp())
+ON CONFLICT (key) DO UPDATE SET
+	tokens = CASE
+		WHEN LEAST(@burst::float8, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at) * @rate::float8) >= 1
+		THEN LEAST(@burst::float8, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at) * @rate::float8) - 1
+		ELSE LEAST(@burst::float8, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at) * @rate::float8)
+	END,
+	allowed = LEAST(@burst::float8, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at) * @rate::float8) >= 1,
+	updated_at = clock_timestamp()
+RETURNING tokens, allowed`
+
+const sweepSQL = `
+DELETE FROM rate_limit_buckets
+WHERE updated_at < clock_timestamp() - @idle::float8 * interval '1 second'`
+
+type postgresLimiter struct {
+	db    database.Database
+	rate  float64
+	burst int
+}
+
+func newPostgresLimiter(db database.Database, rate float64, burst int) *postgresLimiter {
+	return &postgresLimiter{
+		db:    db,
+		rate:  rate,
+		burst: burst,
+	}
+}
+
+func (l *postgresLimiter) Allow(ctx context.Context, key string) (*Result, error) {
+	var row struct {
+		Tokens  float64
+		Allowed bool
+	}
+	err := l.db.GetDb().WithContext(ctx).Raw(takeTokenSQL, map[string]interface{}{
+		"key":   key,
+		"rate":  l.rate,
+		"burst": l.burst,
+	}).Scan(&row).Error
+	if err != nil {
+		return nil, err
+	}
+	return result(row.Allowed, row.Tokens, l.rate, l.burst, time.Now()), nil
+}
+
+func (l *postgresLimiter) Sweep(ctx context.Context) (int64, error) {
+	result := l.db.GetDb().WithContext(ctx).Exec(sweepSQL, map[string]interface{}{
+		"idle": idleAfter(l.rate, l.burst).Seconds(),
+	})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,83 @@
+// Package ratelimit implements per-client token buckets, held in memory for
+// a single instance or in Postgres when several instances share the limits.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/IlhamSetiaji/report-converter/database"
+)
+
+// Result describes the state of a bucket after a request was counted.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is when the bucket will be full again.
+	Reset time.Time
+	// RetryAfter is how long a rejected client should wait for a token.
+	RetryAfter time.Duration
+}
+
+// Limiter takes one token from the bucket of key.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (*Result, error)
+}
+
+// Sweeper is implemented by limiters that keep their buckets outside the
+// process. Sweep removes buckets that have sat idle long enough to be full
+// again, so dropping them changes nothing for their clients, and should be
+// called every SweepInterval.
+type Sweeper interface {
+	Sweep(ctx context.Context) (int64, error)
+}
+
+// SweepInterval is how often idle buckets are looked for.
+const SweepInterval = idleBucketTTL
+
+// NewLimiter returns the limiter selected by ratelimit.backend.
+func NewLimiter(conf *config.Config, db database.Database) (Limiter, error) {
+	if conf.RateLimit == nil || conf.RateLimit.Rate <= 0 || conf.RateLimit.Burst < 1 {
+		return nil, fmt.Errorf("ratelimit.rate and ratelimit.burst must be positive")
+	}
+
+	switch conf.RateLimit.Backend {
+	case "", "memory":
+		return newMemoryLimiter(conf.RateLimit.Rate, conf.RateLimit.Burst), nil
+	case "postgres":
+		return newPostgresLimiter(db, conf.RateLimit.Rate, conf.RateLimit.Burst), nil
+	default:
+		return nil, fmt.Errorf("unknown ratelimit backend %q", conf.RateLimit.Backend)
+	}
+}
+
+// result derives the client-facing numbers from the tokens left in a bucket.
+func result(allowed bool, tokens float64, rate float64, burst int, now time.Time) *Result {
+	r := &Result{
+		Allowed:   allowed,
+		Limit:     burst,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     now.Add(secondsToDuration((float64(burst) - tokens) / rate)),
+	}
+	if !allowed {
+		r.RetryAfter = secondsToDuration((1 - tokens) / rate)
+	}
+	return r
+}
+
+// idleAfter is how long a bucket must go untouched before it is dropped:
+// idleBucketTTL, or longer if the bucket takes longer to refill.
+func idleAfter(rate float64, burst int) time.Duration {
+	return max(idleBucketTTL, secondsToDuration(float64(burst)/rate))
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
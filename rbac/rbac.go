@@ -0,0 +1,106 @@
+// Package rbac defines the permissions guarding the API and the built-in
+// roles that bundle them. Roles are assigned to subjects per tenant.
+package rbac
+
+// Permission allows one kind of action.
+type Permission string
+
+const (
+	PermissionTemplateView    Permission = "template:view"
+	PermissionTemplateRender  Permission = "template:render"
+	PermissionTemplateUpload  Permission = "template:upload"
+	PermissionTemplatePublish Permission = "template:publish"
+	PermissionTemplateDelete  Permission = "template:delete"
+	PermissionApiKeyManage    Permission = "apikey:manage"
+	PermissionRoleManage      Permission = "role:manage"
+	PermissionAuditView       Permission = "audit:view"
+	PermissionUsageView       Permission = "usage:view"
+)
+
+// Role is a named set of permissions.
+type Role string
+
+const (
+	RoleViewer    Role = "viewer"
+	RoleRenderer  Role = "renderer"
+	RoleEditor    Role = "editor"
+	RolePublisher Role = "publisher"
+	RoleAdmin     Role = "admin"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleViewer: {
+		PermissionTemplateView,
+	},
+	RoleRenderer: {
+		PermissionTemplateView,
+		PermissionTemplateRender,
+	},
+	RoleEditor: {
+		PermissionTemplateView,
+		PermissionTemplateRender,
+		PermissionTemplateUpload,
+	},
+	RolePublisher: {
+		PermissionTemplateView,
+		PermissionTemplateRender,
+		PermissionTemplateUpload,
+		PermissionTemplatePublish,
+	},
+	RoleAdmin: AllPermissions(),
+}
+
+// AllPermissions lists every permission.
+func AllPermissions() []Permission {
+	return []Permission{
+		PermissionTemplateView,
+		PermissionTemplateRender,
+		PermissionTemplateUpload,
+		PermissionTemplatePublish,
+		PermissionTemplateDelete,
+		PermissionApiKeyManage,
+		PermissionRoleManage,
+		PermissionAuditView,
+		PermissionUsageView,
+	}
+}
+
+// IsRole reports whether name is a built-in role.
+func IsRole(name string) bool {
+	_, ok := rolePermissions[Role(name)]
+	return ok
+}
+
+// IsPermission reports whether name is a known permission.
+func IsPermission(name string) bool {
+	for _, p := range AllPermissions() {
+		if string(p) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Roles lists the built-in roles with their permissions.
+func Roles() map[Role][]Permission {
+	roles := make(map[Role][]Permission, len(rolePermissions))
+	for role, permissions := range rolePermissions {
+		roles[role] = append([]Permission(nil), permissions...)
+	}
+	return roles
+}
+
+// PermissionSet is the set of permissions a caller holds.
+type PermissionSet map[Permission]bool
+
+// Grant adds the permissions of role to the set. Unknown roles grant nothing.
+func (s PermissionSet) Grant(role Role) {
+	for _, p := range rolePermissions[role] {
+		s[p] = true
+	}
+}
+
+// Has reports whether the set contains p.
+func (s PermissionSet) Has(p Permission) bool {
+	return s[p]
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/database"
+	"github.com/IlhamSetiaji/report-converter/entity"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type IApiKeyRepository interface {
+	CreateApiKey(ctx context.Context, apiKey *entity.ApiKey) (*entity.ApiKey, error)
+	FindAllApiKey(ctx context.Context) ([]*entity.ApiKey, error)
+	FindApiKeyByID(ctx context.Context, id uuid.UUID) (*entity.ApiKey, error)
+	FindApiKeyByHash(ctx context.Context, hash string) (*entity.ApiKey, error)
+	UpdateApiKey(ctx context.Context, id uuid.UUID, updates map[string]interface{}) (*entity.ApiKey, error)
+	TouchApiKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+}
+
+type ApiKeyRepository struct {
+	db     database.Database
+	logger logger.Logger
+}
+
+func NewApiKeyRepository(db database.Database, logger logger.Logger) IApiKeyRepository {
+	return &ApiKeyRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// scoped returns a session bound to ctx that only sees the tenant of ctx.
+func (r *ApiKeyRepository) scoped(ctx context.Context) *gorm.DB {
+	return r.db.GetDb().WithContext(ctx).Scopes(tenantScope(ctx))
+}
+
+func (r *ApiKeyRepository) CreateApiKey(ctx context.Context, apiKey *entity.ApiKey) (*entity.ApiKey, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey.TenantID = tenantID
+	if err := r.db.GetDb().WithContext(ctx).Create(apiKey).Error; err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to create API key")
+		return nil, err
+	}
+	return apiKey, nil
+}
+
+func (r *ApiKeyRepository) FindAllApiKey(ctx context.Context) ([]*entity.ApiKey, error) {
+	var apiKeys []*entity.ApiKey
+	if err := r.scoped(ctx).Order("created_at DESC").Find(&apiKeys).Error; err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to find API keys")
+		return nil, err
+	}
+	return apiKeys, nil
+}
+
+func (r *ApiKeyRepository) FindApiKeyByID(ctx context.Context, id uuid.UUID) (*entity.ApiKey, error) {
+	return r.findApiKey(ctx, "id = ?", id)
+}
+
+// FindApiKeyByHash is used to authenticate a request before its tenant is
+// known, so it is normally called with a tenant.WithAllTenants context.
+func (r *ApiKeyRepository) FindApiKeyByHash(ctx context.Context, hash string) (*entity.ApiKey, error) {
+	return r.findApiKey(ctx, "key_hash = ?", hash)
+}
+
+func (r *ApiKeyRepository) findApiKey(ctx context.Context, query string, arg interface{}) (*entity.ApiKey, error) {
+	var apiKey entity.ApiKey
+	err := r.scoped(ctx).First(&apiKey, query, arg).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to find API key")
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (r *ApiKeyRepository) UpdateApiKey(ctx context.Context, id uuid.UUID, updates map[string]interface{}) (*entity.ApiKey, error) {
+	apiKey, err := r.FindApiKeyByID(ctx, id)
+	if err != nil || apiKey == nil {
+		return nil, err
+	}
+
+	if err := r.scoped(ctx).Model(apiKey).Updates(updates).Error; err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to update API key")
+		return nil, err
+	}
+	return apiKey, nil
+}
+
+// TouchApiKey records when a key was last used. It bypasses hooks so that
+// routine use does not bump updated_at.
+func (r *ApiKeyRepository) TouchApiKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	err := r.scoped(ctx).Model(&entity.ApiKey{}).Where("id = ?", id).UpdateColumn("last_used_at", usedAt).Error
+	if err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to record API key use")
+	}
+	return err
+}
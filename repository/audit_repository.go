@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/database"
+	"github.com/IlhamSetiaji/report-converter/entity"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// auditExportBatchSize bounds how many rows an export holds in memory.
+const auditExportBatchSize = 500
+
+type IAuditRepository interface {
+	CreateAuditLog(ctx context.Context, log *entity.AuditLog) error
+	FindAuditLogs(ctx context.Context, filter *AuditLogFilter) ([]entity.AuditLog, int64, error)
+	ExportAuditLogs(ctx context.Context, filter *AuditLogFilter, fn func([]entity.AuditLog) error) error
+}
+
+// AuditLogFilter narrows an audit log query. Zero values mean "no
+// constraint"; entries are always returned newest first.
+type AuditLogFilter struct {
+	Offset     int
+	Limit      int
+	Actor      string
+	Action     string
+	TemplateID *uuid.UUID
+	From       *time.Time
+	To         *time.Time
+}
+
+type AuditRepository struct {
+	db     database.Database
+	logger logger.Logger
+}
+
+func NewAuditRepository(db database.Database, logger logger.Logger) IAuditRepository {
+	return &AuditRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *AuditRepository) CreateAuditLog(ctx context.Context, log *entity.AuditLog) error {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.TenantID = tenantID
+	if err := r.db.GetDb().WithContext(ctx).Create(log).Error; err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to create audit log")
+		return err
+	}
+	return nil
+}
+
+func (r *AuditRepository) FindAuditLogs(ctx context.Context, filter *AuditLogFilter) ([]entity.AuditLog, int64, error) {
+	query := r.filtered(ctx, filter)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to count audit logs")
+		return nil, 0, err
+	}
+
+	var logs []entity.AuditLog
+	err := query.Order("created_at DESC").Order("id").Offset(filter.Offset).Limit(filter.Limit).Find(&logs).Error
+	if err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to find audit logs")
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+// ExportAuditLogs passes every matching entry to fn in batches, oldest
+// first. Batches are read by keyset so rows written during a long export
+// neither shift nor repeat earlier pages.
+func (r *AuditRepository) ExportAuditLogs(ctx context.Context, filter *AuditLogFilter, fn func([]entity.AuditLog) error) error {
+	var last *entity.AuditLog
+	for {
+		query := r.filtered(ctx, filter)
+		if last != nil {
+			query = query.Where("(created_at, id) > (?, ?)", last.CreatedAt, last.ID)
+		}
+
+		var batch []entity.AuditLog
+		if err := query.Order("created_at").Order("id").Limit(auditExportBatchSize).Find(&batch).Error; err != nil {
+			r.logger.WithContext(ctx).WithError(err).Error("Failed to export audit logs")
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < auditExportBatchSize {
+			return nil
+		}
+		last = &batch[len(batch)-1]
+	}
+}
+
+func (r *AuditRepository) filtered(ctx context.Context, filter *AuditLogFilter) *gorm.DB {
+	query := r.db.GetDb().WithContext(ctx).Scopes(tenantScope(ctx)).Model(&entity.AuditLog{})
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TemplateID != nil {
+		query = query.Where("template_id = ?", *filter.TemplateID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	return query
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/database"
+	"github.com/IlhamSetiaji/report-converter/entity"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"gorm.io/gorm/clause"
+)
+
+// expiredNonceGrace is how long a nonce is kept after its link expires.
+// Links expire at second granularity and are still accepted during the
+// second they expire in, and app instances' clocks may drift a little, so a
+// nonce is only dropped once no instance can still accept its link.
+const expiredNonceGrace = time.Minute
+
+type IDownloadRepository interface {
+	ConsumeNonce(ctx context.Context, nonce string, expiresAt time.Time) (bool, error)
+}
+
+type DownloadRepository struct {
+	db     database.Database
+	logger logger.Logger
+}
+
+func NewDownloadRepository(db database.Database, logger logger.Logger) IDownloadRepository {
+	return &DownloadRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// ConsumeNonce marks a single-use nonce as used. It returns false when the
+// nonce had already been consumed. Nonces of expired links are dropped on the
+// way, once they are past expiredNonceGrace and can no longer pass signature
+// verification.
+func (r *DownloadRepository) ConsumeNonce(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	now := time.Now()
+	err := r.db.GetDb().WithContext(ctx).Where("expires_at < ?", now.Add(-expiredNonceGrace)).Delete(&entity.DownloadNonce{}).Error
+	if err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to delete expired download nonces")
+		return false, err
+	}
+
+	result := r.db.GetDb().WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&entity.DownloadNonce{
+		Nonce:      nonce,
+		ExpiresAt:  expiresAt,
+		ConsumedAt: now,
+	})
+	if result.Error != nil {
+		r.logger.WithContext(ctx).WithError(result.Error).Error("Failed to consume download nonce")
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
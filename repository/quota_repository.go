@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/database"
+	"github.com/IlhamSetiaji/report-converter/entity"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"gorm.io/gorm"
+)
+
+// errQuotaExceeded rolls back a quota transaction once one period is full.
+var errQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaLimit is the number of renders allowed in the period starting at
+// Start.
+type QuotaLimit struct {
+	Period entity.QuotaPeriod
+	Start  time.Time
+	Max    int
+}
+
+type IQuotaRepository interface {
+	ConsumeRenderQuota(ctx context.Context, limits []QuotaLimit) (*QuotaLimit, map[entity.QuotaPeriod]int, error)
+	RefundRenderQuota(ctx context.Context, limits []QuotaLimit) error
+}
+
+type QuotaRepository struct {
+	db     database.Database
+	logger logger.Logger
+}
+
+func NewQuotaRepository(db database.Database, logger logger.Logger) IQuotaRepository {
+	return &QuotaRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// ConsumeRenderQuota counts one render against every limit of the tenant of
+// ctx, or against none of them when one is already used up. It returns the
+// exhausted limit, if any, and otherwise the new count for each period.
+func (r *QuotaRepository) ConsumeRenderQuota(ctx context.Context, limits []QuotaLimit) (*QuotaLimit, map[entity.QuotaPeriod]int, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var exceeded *QuotaLimit
+	counts := make(map[entity.QuotaPeriod]int, len(limits))
+	err = r.db.GetDb().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range limits {
+			var rows []int
+			err := tx.Raw(`
+INSERT INTO render_quota_usages AS q (tenant_id, period, period_start, count)
+VALUES (?, ?, ?, 1)
+ON CONFLICT (tenant_id, period, period_start) DO UPDATE SET count = q.count + 1
+WHERE q.count < ?
+RETURNING count`, tenantID, limits[i].Period, limits[i].Start.Format(time.DateOnly), limits[i].Max).Scan(&rows).Error
+			if err != nil {
+				return err
+			}
+			if len(rows) == 0 {
+				exceeded = &limits[i]
+				return errQuotaExceeded
+			}
+			counts[limits[i].Period] = rows[0]
+		}
+		return nil
+	})
+	if exceeded != nil {
+		return exceeded, nil, nil
+	}
+	if err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to consume render quota")
+		return nil, nil, err
+	}
+	return nil, counts, nil
+}
+
+// RefundRenderQuota takes back one render counted by ConsumeRenderQuota
+// against the same limits.
+func (r *QuotaRepository) RefundRenderQuota(ctx context.Context, limits []QuotaLimit) error {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.GetDb().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, limit := range limits {
+			err := tx.Model(&entity.RenderQuotaUsage{}).
+				Where("tenant_id = ? AND period = ? AND period_start = ? AND count > 0", tenantID, limit.Period, limit.Start.Format(time.DateOnly)).
+				Update("count", gorm.Expr("count - 1")).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to refund render quota")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/IlhamSetiaji/report-converter/database"
+	"github.com/IlhamSetiaji/report-converter/entity"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IRoleRepository interface {
+	CreateRoleAssignment(ctx context.Context, assignment *entity.RoleAssignment) (*entity.RoleAssignment, error)
+	FindRoleAssignments(ctx context.Context, subject string) ([]*entity.RoleAssignment, error)
+	DeleteRoleAssignment(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+type RoleRepository struct {
+	db     database.Database
+	logger logger.Logger
+}
+
+func NewRoleRepository(db database.Database, logger logger.Logger) IRoleRepository {
+	return &RoleRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// scoped returns a session bound to ctx that only sees the tenant of ctx.
+func (r *RoleRepository) scoped(ctx context.Context) *gorm.DB {
+	return r.db.GetDb().WithContext(ctx).Scopes(tenantScope(ctx))
+}
+
+// CreateRoleAssignment is idempotent: assigning a role the subject already
+// holds returns the existing assignment.
+func (r *RoleRepository) CreateRoleAssignment(ctx context.Context, assignment *entity.RoleAssignment) (*entity.RoleAssignment, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment.TenantID = tenantID
+	result := r.db.GetDb().WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(assignment)
+	if result.Error != nil {
+		r.logger.WithContext(ctx).WithError(result.Error).Error("Failed to create role assignment")
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return assignment, nil
+	}
+
+	var existing entity.RoleAssignment
+	err = r.scoped(ctx).First(&existing, "subject = ? AND role = ?", assignment.Subject, assignment.Role).Error
+	if err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to find role assignment")
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// FindRoleAssignments lists the assignments of subject, or of every subject
+// when subject is empty.
+func (r *RoleRepository) FindRoleAssignments(ctx context.Context, subject string) ([]*entity.RoleAssignment, error) {
+	query := r.scoped(ctx)
+	if subject != "" {
+		query = query.Where("subject = ?", subject)
+	}
+
+	var assignments []*entity.RoleAssignment
+	if err := query.Order("subject").Order("role").Find(&assignments).Error; err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to find role assignments")
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// DeleteRoleAssignment removes an assignment for good, so the role can be
+// assigned again later. It reports whether an assignment was removed.
+func (r *RoleRepository) DeleteRoleAssignment(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.scoped(ctx).Unscoped().Where("id = ?", id).Delete(&entity.RoleAssignment{})
+	if result.Error != nil {
+		r.logger.WithContext(ctx).WithError(result.Error).Error("Failed to delete role assignment")
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
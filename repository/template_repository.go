@@ -1,20 +1,74 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"html"
+	"strings"
+	"time"
 
 	"github.com/IlhamSetiaji/report-converter/database"
 	"github.com/IlhamSetiaji/report-converter/entity"
 	"github.com/IlhamSetiaji/report-converter/logger"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ITemplateRepository interface {
-	CreateTemplate(template *entity.Template) (*entity.Template, error)
-	FindAllTemplate() ([]entity.Template, error)
-	FindTemplateByID(id uuid.UUID) (*entity.Template, error)
-	DeleteTemplateByID(id uuid.UUID) error
+	CreateTemplate(ctx context.Context, template *entity.Template) (*entity.Template, error)
+	FindAllTemplate(ctx context.Context, filter *TemplateFilter) ([]entity.Template, int64, error)
+	FindTemplateByID(ctx context.Context, id uuid.UUID) (*entity.Template, error)
+	DeleteTemplateByID(ctx context.Context, id uuid.UUID) error
+	CreateTemplateVersion(ctx context.Context, id uuid.UUID, path string, checksum string, content string) (*entity.Template, error)
+	FindTemplateVersions(ctx context.Context, id uuid.UUID) ([]entity.TemplateVersion, error)
+	FindTemplateVersion(ctx context.Context, id uuid.UUID, version int) (*entity.TemplateVersion, error)
+	UpdateCurrentVersion(ctx context.Context, id uuid.UUID, version *entity.TemplateVersion, content string) (*entity.Template, error)
+	UpdateTemplateStatus(ctx context.Context, id uuid.UUID, from []entity.TemplateStatus, updates map[string]interface{}) (*entity.Template, bool, error)
+	PublishTemplate(ctx context.Context, id uuid.UUID, approvedBy string, approvedAt time.Time) (*entity.Template, bool, error)
+	UpdateTemplateMetadata(ctx context.Context, id uuid.UUID, updates map[string]interface{}) (*entity.Template, error)
+	FindDeletedTemplateByID(ctx context.Context, id uuid.UUID) (*entity.Template, error)
+	FindDeletedTemplatesBefore(ctx context.Context, before time.Time) ([]entity.Template, error)
+	RestoreTemplateByID(ctx context.Context, id uuid.UUID) error
+	PurgeTemplateByID(ctx context.Context, id uuid.UUID) error
+	SearchTemplates(ctx context.Context, query string, offset int, limit int) ([]TemplateSearchResult, int64, error)
+}
+
+// TemplateFilter narrows and orders a template listing. Zero values mean
+// "no constraint"; Sort must already be a known column name.
+type TemplateFilter struct {
+	Offset       int
+	Limit        int
+	TemplateType string
+	Status       string
+	Category     string
+	Tags         []string
+	Owner        string
+	OutputFormat string
+	Search       string
+	CreatedFrom  *time.Time
+	CreatedTo    *time.Time
+	Sort         string
+	Desc         bool
+}
+
+// Search headlines are marked with private-use characters rather than HTML,
+// so the excerpt can be escaped before the matches are wrapped in <mark>.
+// The characters are stripped from the content first and cannot be forged.
+const (
+	headlineStart = "\uE000"
+	headlineStop  = "\uE001"
+)
+
+var headlineMarker = strings.NewReplacer(headlineStart, "<mark>", headlineStop, "</mark>")
+
+// TemplateSearchResult is a template matched by full-text search together
+// with a highlighted excerpt of the matching content. Snippet is HTML: the
+// content is escaped and matches are wrapped in <mark>.
+type TemplateSearchResult struct {
+	entity.Template
+	Snippet string
+	Rank    float64
 }
 
 type TemplateRepository struct {
@@ -29,54 +83,416 @@ func NewTemplateRepository(db database.Database, logger logger.Logger) ITemplate
 	}
 }
 
-func (r *TemplateRepository) CreateTemplate(template *entity.Template) (*entity.Template, error) {
-	err := r.db.GetDb().Create(template).Error
+// scoped returns a session bound to ctx that only sees the tenant of ctx.
+func (r *TemplateRepository) scoped(ctx context.Context) *gorm.DB {
+	return r.db.GetDb().WithContext(ctx).Scopes(tenantScope(ctx))
+}
+
+func (r *TemplateRepository) CreateTemplate(ctx context.Context, template *entity.Template) (*entity.Template, error) {
+	tenantID, err := requireTenant(ctx)
 	if err != nil {
-		r.logger.GetLogger().Error("Failed to create template", err)
+		return nil, err
+	}
+
+	err = r.db.GetDb().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		template.TenantID = tenantID
+		template.CurrentVersion = 1
+		versions := template.Versions
+		template.Versions = nil
+		if err := tx.Create(template).Error; err != nil {
+			return err
+		}
+
+		for i := range versions {
+			versions[i].TemplateID = template.ID
+			versions[i].TenantID = tenantID
+			versions[i].Version = i + 1
+		}
+		if len(versions) > 0 {
+			if err := tx.Create(&versions).Error; err != nil {
+				return err
+			}
+		}
+		template.Versions = versions
+		return nil
+	})
+	if err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to create template")
 		return nil, err
 	}
 	return template, nil
 }
 
-func (r *TemplateRepository) FindAllTemplate() ([]entity.Template, error) {
+func (r *TemplateRepository) FindAllTemplate(ctx context.Context, filter *TemplateFilter) ([]entity.Template, int64, error) {
+	query := r.scoped(ctx).Model(&entity.Template{})
+	if filter.TemplateType != "" {
+		query = query.Where("template_type = ?", filter.TemplateType)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if len(filter.Tags) > 0 {
+		// A template must carry every requested tag.
+		query = query.Where("tags @> ?::jsonb", entity.StringArray(filter.Tags))
+	}
+	if filter.Owner != "" {
+		query = query.Where("owner = ?", filter.Owner)
+	}
+	if filter.OutputFormat != "" {
+		query = query.Where("output_format = ?", filter.OutputFormat)
+	}
+	if filter.Search != "" {
+		query = query.Where("name ILIKE ?", "%"+escapeLike(filter.Search)+"%")
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to count templates")
+		return nil, 0, err
+	}
+
 	var templates []entity.Template
-	err := r.db.GetDb().Find(&templates).Error
+	err := query.
+		Order(clause.OrderByColumn{Column: clause.Column{Name: filter.Sort}, Desc: filter.Desc}).
+		Order("id").
+		Offset(filter.Offset).
+		Limit(filter.Limit).
+		Find(&templates).Error
 	if err != nil {
-		r.logger.GetLogger().Error("Failed to find all templates", err)
-		return nil, err
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to find all templates")
+		return nil, 0, err
 	}
-	return templates, nil
+	return templates, total, nil
 }
 
-func (r *TemplateRepository) FindTemplateByID(id uuid.UUID) (*entity.Template, error) {
+func (r *TemplateRepository) FindTemplateByID(ctx context.Context, id uuid.UUID) (*entity.Template, error) {
 	var template entity.Template
-	err := r.db.GetDb().First(&template, "id = ?", id).Error
+	err := r.scoped(ctx).First(&template, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			r.logger.GetLogger().Error("Template not found", err)
+			r.logger.WithContext(ctx).WithError(err).Debug("Template not found")
 			return nil, nil
 		}
-		r.logger.GetLogger().Error("Failed to find template by ID", err)
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to find template by ID")
+		return nil, err
 	}
 	return &template, nil
 }
 
-func (r *TemplateRepository) DeleteTemplateByID(id uuid.UUID) error {
+func (r *TemplateRepository) DeleteTemplateByID(ctx context.Context, id uuid.UUID) error {
 	var template entity.Template
-	err := r.db.GetDb().First(&template, "id = ?", id).Error
+	err := r.scoped(ctx).First(&template, "id = ?", id).Error
 	if err != nil {
-		if err.Error() == "record not found" {
-			r.logger.GetLogger().Error("Template not found", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.logger.WithContext(ctx).WithError(err).Debug("Template not found")
 			return nil
 		}
-		r.logger.GetLogger().Error("Failed to find template by ID", err)
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to find template by ID")
+		return err
+	}
+
+	err = r.scoped(ctx).Delete(&template).Error
+	if err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to delete template")
+		return err
+	}
+	return nil
+}
+
+func (r *TemplateRepository) CreateTemplateVersion(ctx context.Context, id uuid.UUID, path string, checksum string, content string) (*entity.Template, error) {
+	var template entity.Template
+	err := r.db.GetDb().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Scopes(tenantScope(ctx)).Clauses(clause.Locking{Strength: "UPDATE"}).First(&template, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		var latest int
+		if err := tx.Model(&entity.TemplateVersion{}).Scopes(tenantScope(ctx)).
+			Where("template_id = ?", id).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+
+		version := entity.TemplateVersion{
+			TenantID:   template.TenantID,
+			TemplateID: id,
+			Version:    latest + 1,
+			Path:       path,
+			Checksum:   checksum,
+		}
+		if err := tx.Create(&version).Error; err != nil {
+			return err
+		}
+
+		template.CurrentVersion = version.Version
+		template.Path = version.Path
+		template.Content = content
+		template.Status = reopenedStatus(template.Status)
+		return tx.Model(&template).Updates(map[string]interface{}{
+			"current_version": template.CurrentVersion,
+			"path":            template.Path,
+			"content":         template.Content,
+			"status":          template.Status,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.logger.WithContext(ctx).WithError(err).Debug("Template not found")
+			return nil, nil
+		}
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to create template version")
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *TemplateRepository) FindTemplateVersions(ctx context.Context, id uuid.UUID) ([]entity.TemplateVersion, error) {
+	var versions []entity.TemplateVersion
+	err := r.scoped(ctx).Where("template_id = ?", id).Order("version DESC").Find(&versions).Error
+	if err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to find template versions")
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (r *TemplateRepository) FindTemplateVersion(ctx context.Context, id uuid.UUID, version int) (*entity.TemplateVersion, error) {
+	var templateVersion entity.TemplateVersion
+	err := r.scoped(ctx).First(&templateVersion, "template_id = ? AND version = ?", id, version).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.logger.WithContext(ctx).WithError(err).Debug("Template version not found")
+			return nil, nil
+		}
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to find template version")
+		return nil, err
+	}
+	return &templateVersion, nil
+}
+
+// UpdateCurrentVersion makes version the template's working copy, with
+// content as its extracted text. Like CreateTemplateVersion it locks the
+// template row, so a concurrent new version or rollback cannot interleave.
+func (r *TemplateRepository) UpdateCurrentVersion(ctx context.Context, id uuid.UUID, version *entity.TemplateVersion, content string) (*entity.Template, error) {
+	var template entity.Template
+	err := r.db.GetDb().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Scopes(tenantScope(ctx)).Clauses(clause.Locking{Strength: "UPDATE"}).First(&template, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		template.CurrentVersion = version.Version
+		template.Path = version.Path
+		template.Content = content
+		template.Status = reopenedStatus(template.Status)
+		return tx.Model(&template).Updates(map[string]interface{}{
+			"current_version": template.CurrentVersion,
+			"path":            template.Path,
+			"content":         template.Content,
+			"status":          template.Status,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.logger.WithContext(ctx).WithError(err).Debug("Template not found")
+			return nil, nil
+		}
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to update current template version")
+		return nil, err
+	}
+	return &template, nil
+}
+
+// UpdateTemplateStatus applies updates only while the template is in one of
+// the from statuses, so concurrent transitions cannot both succeed. The bool
+// result reports whether the transition was applied.
+func (r *TemplateRepository) UpdateTemplateStatus(ctx context.Context, id uuid.UUID, from []entity.TemplateStatus, updates map[string]interface{}) (*entity.Template, bool, error) {
+	result := r.scoped(ctx).Model(&entity.Template{}).
+		Where("id = ? AND status IN ?", id, from).
+		Updates(updates)
+	if result.Error != nil {
+		r.logger.WithContext(ctx).WithError(result.Error).Error("Failed to update template status")
+		return nil, false, result.Error
+	}
+
+	template, err := r.FindTemplateByID(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	return template, result.RowsAffected > 0, nil
+}
+
+// PublishTemplate approves a template under review: its current version
+// becomes the published one and is marked published, unless an earlier
+// approval already did. Like UpdateTemplateStatus, the bool result reports
+// whether the template was in review.
+func (r *TemplateRepository) PublishTemplate(ctx context.Context, id uuid.UUID, approvedBy string, approvedAt time.Time) (*entity.Template, bool, error) {
+	var applied bool
+	err := r.db.GetDb().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var template entity.Template
+		result := tx.Model(&template).Scopes(tenantScope(ctx)).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "current_version"}}}).
+			Where("id = ? AND status = ?", id, entity.TemplateStatusInReview).
+			Updates(map[string]interface{}{
+				"status":            entity.TemplateStatusPublished,
+				"approved_by":       approvedBy,
+				"approved_at":       approvedAt,
+				"published_version": gorm.Expr("current_version"),
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		applied = true
+
+		return tx.Model(&entity.TemplateVersion{}).Scopes(tenantScope(ctx)).
+			Where("template_id = ? AND version = ? AND published_at IS NULL", id, template.CurrentVersion).
+			Update("published_at", approvedAt).Error
+	})
+	if err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to publish template")
+		return nil, false, err
+	}
+
+	template, err := r.FindTemplateByID(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	return template, applied, nil
+}
+
+// reopenedStatus is the status of a template once its current version has
+// changed. A new working copy has not been reviewed, so a template under
+// review or published goes back to draft; the published version stays live.
+func reopenedStatus(status entity.TemplateStatus) entity.TemplateStatus {
+	if status == entity.TemplateStatusInReview || status == entity.TemplateStatusPublished {
+		return entity.TemplateStatusDraft
+	}
+	return status
+}
+
+func (r *TemplateRepository) UpdateTemplateMetadata(ctx context.Context, id uuid.UUID, updates map[string]interface{}) (*entity.Template, error) {
+	template, err := r.FindTemplateByID(ctx, id)
+	if err != nil || template == nil {
+		return template, err
+	}
+
+	if len(updates) > 0 {
+		err = r.scoped(ctx).Model(template).Updates(updates).Error
+		if err != nil {
+			r.logger.WithContext(ctx).WithError(err).Error("Failed to update template metadata")
+			return nil, err
+		}
+	}
+	return r.FindTemplateByID(ctx, id)
+}
+
+func (r *TemplateRepository) SearchTemplates(ctx context.Context, query string, offset int, limit int) ([]TemplateSearchResult, int64, error) {
+	tsQuery := gorm.Expr("websearch_to_tsquery('simple', ?)", query)
+	base := r.scoped(ctx).Model(&entity.Template{}).Where("search_vector @@ ?", tsQuery)
+
+	var total int64
+	if err := base.Count(&total).Error; err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to count template search results")
+		return nil, 0, err
+	}
+
+	var results []TemplateSearchResult
+	err := base.
+		Select("templates.*, ts_headline('simple', translate(coalesce(content, ''), ?, ''), ?, ?) AS snippet, ts_rank(search_vector, ?) AS rank",
+			headlineStart+headlineStop, tsQuery,
+			`StartSel="`+headlineStart+`", StopSel="`+headlineStop+`", MaxFragments=3, FragmentDelimiter=" ... "`,
+			tsQuery).
+		Order("rank DESC").
+		Order("id").
+		Offset(offset).
+		Limit(limit).
+		Scan(&results).Error
+	if err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to search templates")
+		return nil, 0, err
+	}
+	for i := range results {
+		results[i].Snippet = highlightSnippet(results[i].Snippet)
+	}
+	return results, total, nil
+}
+
+// highlightSnippet escapes a headline built with the private-use delimiters
+// and turns the delimiters into <mark> elements.
+func highlightSnippet(headline string) string {
+	return headlineMarker.Replace(html.EscapeString(headline))
+}
+
+// FindDeletedTemplateByID returns a soft-deleted template with its versions,
+// or nil when the template does not exist or has not been deleted.
+func (r *TemplateRepository) FindDeletedTemplateByID(ctx context.Context, id uuid.UUID) (*entity.Template, error) {
+	var template entity.Template
+	err := r.scoped(ctx).Unscoped().
+		Preload("Versions").
+		Where("deleted_at IS NOT NULL").
+		First(&template, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.logger.WithContext(ctx).WithError(err).Debug("Deleted template not found")
+			return nil, nil
+		}
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to find deleted template by ID")
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *TemplateRepository) FindDeletedTemplatesBefore(ctx context.Context, before time.Time) ([]entity.Template, error) {
+	var templates []entity.Template
+	err := r.scoped(ctx).Unscoped().
+		Preload("Versions").
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Find(&templates).Error
+	if err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to find deleted templates")
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (r *TemplateRepository) RestoreTemplateByID(ctx context.Context, id uuid.UUID) error {
+	err := r.scoped(ctx).Unscoped().Model(&entity.Template{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil).Error
+	if err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to restore template")
 		return err
 	}
+	return nil
+}
 
-	err = r.db.GetDb().Delete(&template).Error
+// PurgeTemplateByID permanently removes a template and all of its versions.
+func (r *TemplateRepository) PurgeTemplateByID(ctx context.Context, id uuid.UUID) error {
+	err := r.db.GetDb().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Scopes(tenantScope(ctx)).Where("template_id = ?", id).Delete(&entity.TemplateVersion{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Scopes(tenantScope(ctx)).Where("id = ?", id).Delete(&entity.Template{}).Error
+	})
 	if err != nil {
-		r.logger.GetLogger().Error("Failed to delete template", err)
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to purge template")
 		return err
 	}
 	return nil
 }
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike escapes the LIKE wildcards in s so user input matches literally.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "testing"
+
+func TestHighlightSnippetEscapesContent(t *testing.T) {
+	headline := `<img src=x onerror=alert(1)> ` + headlineStart + `invoice` + headlineStop + ` & <mark>total</mark>`
+	want := `&lt;img src=x onerror=alert(1)&gt; <mark>invoice</mark> &amp; &lt;mark&gt;total&lt;/mark&gt;`
+	if got := highlightSnippet(headline); got != want {
+		t.Errorf("highlightSnippet = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/IlhamSetiaji/report-converter/tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tenantScope restricts a query to the tenant of ctx. A query without a
+// tenant fails with tenant.ErrMissing instead of silently seeing every
+// tenant; only contexts from tenant.WithAllTenants are left unrestricted.
+func tenantScope(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenant.IsAllTenants(ctx) {
+			return db
+		}
+
+		id := tenant.FromContext(ctx)
+		if id == "" {
+			db.AddError(tenant.ErrMissing)
+			return db
+		}
+		return db.Where(clause.Eq{
+			Column: clause.Column{Table: clause.CurrentTable, Name: "tenant_id"},
+			Value:  id,
+		})
+	}
+}
+
+// requireTenant returns the tenant new rows are created for.
+func requireTenant(ctx context.Context) (string, error) {
+	id := tenant.FromContext(ctx)
+	if id == "" {
+		return "", tenant.ErrMissing
+	}
+	return id, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/database"
+	"github.com/IlhamSetiaji/report-converter/entity"
+	"github.com/IlhamSetiaji/report-converter/logger"
+)
+
+type IUsageRepository interface {
+	CreateUsageRecord(ctx context.Context, record *entity.UsageRecord) error
+	AggregateUsage(ctx context.Context, filter *UsageFilter) ([]UsageAggregate, error)
+}
+
+// UsageFilter selects the usage to aggregate. Period is "day" or "month"
+// and must already be validated; buckets follow the Asia/Jakarta calendar.
+type UsageFilter struct {
+	Period         string
+	From           *time.Time
+	To             *time.Time
+	IncludePreview bool
+}
+
+// UsageAggregate is the usage of one tenant in one day or month.
+type UsageAggregate struct {
+	TenantID    string
+	PeriodStart time.Time
+	Renders     int64
+	Pages       int64
+	OutputBytes int64
+	CPUMillis   int64 `gorm:"column:cpu_millis"`
+	WallMillis  int64
+}
+
+type UsageRepository struct {
+	db     database.Database
+	logger logger.Logger
+}
+
+func NewUsageRepository(db database.Database, logger logger.Logger) IUsageRepository {
+	return &UsageRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *UsageRepository) CreateUsageRecord(ctx context.Context, record *entity.UsageRecord) error {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	record.TenantID = tenantID
+	if err := r.db.GetDb().WithContext(ctx).Create(record).Error; err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to create usage record")
+		return err
+	}
+	return nil
+}
+
+// AggregateUsage sums usage per tenant and period. With a
+// tenant.WithAllTenants context it covers every tenant.
+func (r *UsageRepository) AggregateUsage(ctx context.Context, filter *UsageFilter) ([]UsageAggregate, error) {
+	bucket := "date_trunc('" + filter.Period + "', created_at AT TIME ZONE 'Asia/Jakarta')"
+	query := r.db.GetDb().WithContext(ctx).Scopes(tenantScope(ctx)).Model(&entity.UsageRecord{})
+	if !filter.IncludePreview {
+		query = query.Where("preview = ?", false)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var aggregates []UsageAggregate
+	err := query.
+		Select("tenant_id, " + bucket + " AS period_start, COUNT(*) AS renders, " +
+			"COALESCE(SUM(page_count), 0) AS pages, COALESCE(SUM(output_bytes), 0) AS output_bytes, " +
+			"COALESCE(SUM(cpu_millis), 0) AS cpu_millis, COALESCE(SUM(wall_millis), 0) AS wall_millis").
+		Group("tenant_id, period_start").
+		Order("period_start, tenant_id").
+		Scan(&aggregates).Error
+	if err != nil {
+		r.logger.WithContext(ctx).WithError(err).Error("Failed to aggregate usage")
+		return nil, err
+	}
+	return aggregates, nil
+}
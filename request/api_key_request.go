@@ -0,0 +1,7 @@
+package request
+
+type CreateApiKeyRequest struct {
+	Name      string   `json:"name" validate:"required,max=255"`
+	Scopes    []string `json:"scopes" validate:"omitempty,dive,required,permission"`
+	ExpiresAt string   `json:"expires_at" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
@@ -0,0 +1,11 @@
+package request
+
+type AuditLogListRequest struct {
+	Page       int    `form:"page" validate:"omitempty,min=1,max=10000"`
+	Limit      int    `form:"limit" validate:"omitempty,min=1,max=100"`
+	Actor      string `form:"actor" validate:"omitempty,max=255"`
+	Action     string `form:"action" validate:"omitempty,max=64"`
+	TemplateID string `form:"template_id" validate:"omitempty,uuid"`
+	From       string `form:"from" validate:"omitempty,datetime=2006-01-02"`
+	To         string `form:"to" validate:"omitempty,datetime=2006-01-02"`
+}
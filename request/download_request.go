@@ -0,0 +1,14 @@
+package request
+
+type DownloadRequest struct {
+	Key       string `form:"key" validate:"required"`
+	Expires   int64  `form:"expires" validate:"required"`
+	Nonce     string `form:"nonce" validate:"omitempty,hexadecimal,len=32"`
+	Signature string `form:"signature" validate:"required"`
+}
+
+type DownloadURLRequest struct {
+	Version   int  `form:"version" validate:"omitempty,min=1"`
+	SingleUse bool `form:"single_use"`
+	ExpiresIn int  `form:"expires_in" validate:"omitempty,min=1,max=86400"`
+}
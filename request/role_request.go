@@ -0,0 +1,10 @@
+package request
+
+type AssignRoleRequest struct {
+	Subject string `json:"subject" validate:"required,max=255"`
+	Role    string `json:"role" validate:"required,role"`
+}
+
+type RoleAssignmentListRequest struct {
+	Subject string `form:"subject" validate:"omitempty,max=255"`
+}
@@ -4,12 +4,64 @@ import "mime/multipart"
 
 type TemplateRequest struct {
 	Name         string                `form:"name" validate:"required"`
-	TemplateType string                `form:"template_type" validate:"required"`
+	TemplateType string                `form:"template_type" validate:"required,template_type"`
 	File         *multipart.FileHeader `form:"file" validate:"required"`
 	Path         string                `form:"path" validate:"omitempty"`
+	Description  string                `form:"description" validate:"omitempty,max=2000"`
+	Category     string                `form:"category" validate:"omitempty,max=100"`
+	Tags         []string              `form:"tags" validate:"omitempty,max=20,dive,required,max=50"`
+	Owner        string                `form:"owner" validate:"omitempty,max=255"`
+	SampleData   string                `form:"sample_data" validate:"omitempty,json"`
+	OutputFormat string                `form:"output_format" validate:"omitempty,output_format=TemplateType"`
+}
+
+type UpdateTemplateMetadataRequest struct {
+	Name         *string                `json:"name" validate:"omitempty,min=1,max=255"`
+	Description  *string                `json:"description" validate:"omitempty,max=2000"`
+	Category     *string                `json:"category" validate:"omitempty,max=100"`
+	Tags         []string               `json:"tags" validate:"omitempty,max=20,dive,required,max=50"`
+	Owner        *string                `json:"owner" validate:"omitempty,max=255"`
+	SampleData   map[string]interface{} `json:"sample_data" validate:"omitempty"`
+	OutputFormat *string                `json:"output_format" validate:"omitempty,output_format"`
 }
 
 type GeneratePDFRequest struct {
-	TemplateID string                 `json:"template_id" validate:"required"`
-	Data       map[string]interface{} `json:"data" validate:"required"`
+	TemplateID   string                 `json:"template_id" validate:"required"`
+	Data         map[string]interface{} `json:"data" validate:"required"`
+	OutputFormat string                 `json:"output_format" validate:"omitempty,output_format"`
+}
+
+type ReplaceTemplateFileRequest struct {
+	File *multipart.FileHeader `form:"file" validate:"required"`
+	Path string                `form:"path" validate:"omitempty"`
+}
+
+type RollbackTemplateRequest struct {
+	Version int `json:"version" validate:"required,min=1"`
+}
+
+type ReviewTemplateRequest struct {
+	ReviewedBy string `json:"reviewed_by" validate:"required"`
+}
+
+type TemplateListRequest struct {
+	Page         int      `form:"page" validate:"omitempty,min=1,max=10000"`
+	Limit        int      `form:"limit" validate:"omitempty,min=1,max=100"`
+	TemplateType string   `form:"template_type" validate:"omitempty,template_type"`
+	Status       string   `form:"status" validate:"omitempty,oneof=draft in_review published archived"`
+	Category     string   `form:"category" validate:"omitempty,max=100"`
+	Tags         []string `form:"tags" validate:"omitempty,dive,required,max=50"`
+	Owner        string   `form:"owner" validate:"omitempty,max=255"`
+	OutputFormat string   `form:"output_format" validate:"omitempty,output_format=TemplateType"`
+	Search       string   `form:"search" validate:"omitempty,max=255"`
+	CreatedFrom  string   `form:"created_from" validate:"omitempty,datetime=2006-01-02"`
+	CreatedTo    string   `form:"created_to" validate:"omitempty,datetime=2006-01-02"`
+	Sort         string   `form:"sort" validate:"omitempty,oneof=name template_type status created_at updated_at"`
+	Order        string   `form:"order" validate:"omitempty,oneof=asc desc"`
+}
+
+type TemplateSearchRequest struct {
+	Query string `form:"q" validate:"required,max=255"`
+	Page  int    `form:"page" validate:"omitempty,min=1,max=10000"`
+	Limit int    `form:"limit" validate:"omitempty,min=1,max=100"`
 }
@@ -0,0 +1,9 @@
+package request
+
+type UsageReportRequest struct {
+	Period         string `form:"period" validate:"omitempty,oneof=day month"`
+	From           string `form:"from" validate:"omitempty,datetime=2006-01-02"`
+	To             string `form:"to" validate:"omitempty,datetime=2006-01-02"`
+	IncludePreview bool   `form:"include_preview"`
+	Format         string `form:"format" validate:"omitempty,oneof=json csv"`
+}
@@ -0,0 +1,20 @@
+package response
+
+type ApiKeyResponse struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	CreatedBy  string   `json:"created_by"`
+	ExpiresAt  string   `json:"expires_at,omitempty"`
+	LastUsedAt string   `json:"last_used_at,omitempty"`
+	RevokedAt  string   `json:"revoked_at,omitempty"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+// ApiKeySecretResponse is only returned when a key is created or rotated;
+// the plain key cannot be retrieved afterwards.
+type ApiKeySecretResponse struct {
+	*ApiKeyResponse
+	Key string `json:"key"`
+}
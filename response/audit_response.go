@@ -0,0 +1,14 @@
+package response
+
+type AuditLogResponse struct {
+	ID              string                 `json:"id"`
+	Actor           string                 `json:"actor"`
+	Action          string                 `json:"action"`
+	TemplateID      string                 `json:"template_id,omitempty"`
+	TemplateVersion int                    `json:"template_version,omitempty"`
+	IP              string                 `json:"ip"`
+	UserAgent       string                 `json:"user_agent"`
+	DataHash        string                 `json:"data_hash,omitempty"`
+	Details         map[string]interface{} `json:"details,omitempty"`
+	CreatedAt       string                 `json:"created_at"`
+}
@@ -0,0 +1,7 @@
+package response
+
+type DownloadURLResponse struct {
+	Url       string `json:"url"`
+	ExpiresAt string `json:"expires_at"`
+	SingleUse bool   `json:"single_use"`
+}
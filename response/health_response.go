@@ -0,0 +1,12 @@
+package response
+
+type HealthResponse struct {
+	Status string                         `json:"status"`
+	Checks map[string]HealthCheckResponse `json:"checks,omitempty"`
+}
+
+// HealthCheckResponse is public, so it names the outcome only; the cause of
+// a failure is logged.
+type HealthCheckResponse struct {
+	Status string `json:"status"`
+}
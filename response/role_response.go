@@ -0,0 +1,14 @@
+package response
+
+type RoleResponse struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+type RoleAssignmentResponse struct {
+	ID        string `json:"id"`
+	Subject   string `json:"subject"`
+	Role      string `json:"role"`
+	GrantedBy string `json:"granted_by"`
+	CreatedAt string `json:"created_at"`
+}
@@ -1,9 +1,46 @@
 package response
 
 type TemplateResponse struct {
+	ID           string                 `json:"id"`
+	Name         string                 `json:"name"`
+	TemplateType string                 `json:"template_type"`
+	Path         string                 `json:"path"`
+	PathOriginal string                 `json:"path_original"`
+	Description  string                 `json:"description"`
+	Category     string                 `json:"category"`
+	Tags         []string               `json:"tags"`
+	Owner        string                 `json:"owner"`
+	SampleData   map[string]interface{} `json:"sample_data,omitempty"`
+	OutputFormat string                 `json:"output_format"`
+	Version      int                    `json:"version"`
+	// PublishedVersion is the version generate-pdf renders, 0 when none is.
+	PublishedVersion int    `json:"published_version"`
+	Status           string `json:"status"`
+	ApprovedBy       string `json:"approved_by,omitempty"`
+	ApprovedAt       string `json:"approved_at,omitempty"`
+}
+
+type TemplateVersionResponse struct {
 	ID           string `json:"id"`
-	Name         string `json:"name"`
-	TemplateType string `json:"template_type"`
+	TemplateID   string `json:"template_id"`
+	Version      int    `json:"version"`
 	Path         string `json:"path"`
 	PathOriginal string `json:"path_original"`
+	Checksum     string `json:"checksum"`
+	IsCurrent    bool   `json:"is_current"`
+	PublishedAt  string `json:"published_at,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type TemplateSearchResponse struct {
+	*TemplateResponse
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
+}
+
+type TemplateTypeResponse struct {
+	Type          string   `json:"type"`
+	MimeTypes     []string `json:"mime_types"`
+	Extension     string   `json:"extension"`
+	OutputFormats []string `json:"output_formats"`
 }
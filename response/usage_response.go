@@ -0,0 +1,12 @@
+package response
+
+type UsageResponse struct {
+	TenantID    string `json:"tenant_id"`
+	Period      string `json:"period"`
+	PeriodStart string `json:"period_start"`
+	Renders     int64  `json:"renders"`
+	Pages       int64  `json:"pages"`
+	OutputBytes int64  `json:"output_bytes"`
+	CPUMillis   int64  `json:"cpu_millis"`
+	WallMillis  int64  `json:"wall_millis"`
+}
@@ -0,0 +1,8 @@
+package response
+
+// FieldErrorResponse describes one field that failed validation.
+type FieldErrorResponse struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
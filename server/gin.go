@@ -1,39 +1,73 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"os/signal"
 	"strconv"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/IlhamSetiaji/report-converter/auth"
 	"github.com/IlhamSetiaji/report-converter/config"
 	"github.com/IlhamSetiaji/report-converter/database"
 	"github.com/IlhamSetiaji/report-converter/dto"
 	"github.com/IlhamSetiaji/report-converter/handler"
+	"github.com/IlhamSetiaji/report-converter/health"
 	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/middleware"
+	"github.com/IlhamSetiaji/report-converter/openapi"
+	"github.com/IlhamSetiaji/report-converter/ratelimit"
+	"github.com/IlhamSetiaji/report-converter/rbac"
 	"github.com/IlhamSetiaji/report-converter/repository"
+	"github.com/IlhamSetiaji/report-converter/signer"
+	"github.com/IlhamSetiaji/report-converter/storage"
+	"github.com/IlhamSetiaji/report-converter/tenant"
+	"github.com/IlhamSetiaji/report-converter/tracing"
+	"github.com/IlhamSetiaji/report-converter/upload"
 	"github.com/IlhamSetiaji/report-converter/usecase"
 	"github.com/IlhamSetiaji/report-converter/validator"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type ginServer struct {
 	app       *gin.Engine
 	db        database.Database
+	storage   storage.Storage
+	signer    signer.URLSigner
 	conf      config.Config
 	log       logger.Logger
 	validator validator.Validator
+	api       *gin.RouterGroup
+	roles     usecase.IRoleUseCase
+	usage     usecase.IUsageUseCase
+	health    *health.Checker
+	// ctx is cancelled on SIGINT or SIGTERM; background workers stop with it
+	// and are tracked by workers so shutdown can wait for them.
+	ctx     context.Context
+	workers sync.WaitGroup
 }
 
-func NewGinServer(db database.Database, conf config.Config, log logger.Logger, validator validator.Validator) Server {
+func NewGinServer(db database.Database, storage storage.Storage, signer signer.URLSigner, conf config.Config, log logger.Logger, validator validator.Validator) Server {
 	app := gin.New()
-	app.Use(gin.Recovery())
-	app.Use(gin.Logger())
+	app.Use(middleware.RequestID())
+	app.Use(middleware.AccessLog(log))
+	app.Use(middleware.Recover(log))
+	app.Use(middleware.HandleErrors(log, validator))
 	app.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"https://prasi.avolut.com", "https://wareify.avolut.com", "https://eam.avolut.com"}, // Frontend URL
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowOrigins: []string{"https://prasi.avolut.com", "https://wareify.avolut.com", "https://eam.avolut.com"}, // Frontend URL
+		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "traceparent", "tracestate", middleware.RequestIDHeader},
+		ExposeHeaders: []string{
+			"Content-Length", "Content-Disposition", "X-Template-Version", "X-Output-Url", middleware.RequestIDHeader,
+			"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After",
+			"X-Quota-Period", "X-Quota-Limit", "X-Quota-Remaining", "X-Quota-Reset",
+		},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -43,34 +77,262 @@ func NewGinServer(db database.Database, conf config.Config, log logger.Logger, v
 	return &ginServer{
 		app:       app,
 		db:        db,
+		storage:   storage,
+		signer:    signer,
 		conf:      conf,
 		log:       log,
 		validator: validator,
 	}
 }
 
+// Start serves until SIGINT or SIGTERM, then shuts down gracefully: it
+// reports not ready, stops taking connections, waits for in-flight requests
+// (and with them conversions) for at most server.shutdowntimeout, and stops the
+// background workers.
 func (g *ginServer) Start() {
-	g.app.Static("/storage", "./storage")
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	g.ctx = ctx
+
+	g.registerRoutes()
+
+	srv := &http.Server{
+		Addr:    ":" + strconv.Itoa(g.conf.Server.Port),
+		Handler: g.app,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+	g.log.GetLogger().WithField("port", g.conf.Server.Port).Info("Server started")
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			g.log.GetLogger().WithError(err).Error("Server stopped")
+		}
+		stop()
+	case <-ctx.Done():
+	}
+
+	g.log.GetLogger().Info("Shutting down")
+	// Fail readiness first and give load balancers server.draindelay to stop
+	// routing here before the listener closes.
+	g.health.Drain()
+	time.Sleep(g.conf.Server.DrainDelay)
+
+	timeout := g.conf.Server.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		g.log.GetLogger().WithError(err).Error("Failed to drain in-flight requests")
+	}
+	g.workers.Wait()
+	g.log.GetLogger().Info("Server stopped")
+}
+
+// registerRoutes installs the remaining middleware and every route. Workers
+// the routes need are started on g.ctx.
+func (g *ginServer) registerRoutes() {
 	g.app.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("App-Name", g.conf.Server.Name)
 	})
+	if g.conf.Metrics != nil && g.conf.Metrics.Enabled {
+		g.app.Use(middleware.Metrics())
+	}
+	g.app.Use(middleware.Trace())
 	g.app.GET("/", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Welcome to the User Service API",
 			"status":  "OK",
 		})
 	})
-	g.app.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"message": "Service is running",
-			"status":  "OK",
-		})
-	})
+	g.initializeHealthHandler()
+	if g.conf.Metrics != nil && g.conf.Metrics.Enabled {
+		g.app.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	}
+
+	g.roles = usecase.NewRoleUseCase(repository.NewRoleRepository(g.db, g.log), &g.conf)
+	apiKeyUseCase := usecase.NewApiKeyUseCase(repository.NewApiKeyRepository(g.db, g.log), dto.NewApiKeyDTO(), g.roles)
+	g.usage = usecase.NewUsageUseCase(repository.NewUsageRepository(g.db, g.log))
 
+	g.api = g.app.Group("/api/v1", g.authMiddleware(apiKeyUseCase)...)
 	g.initializeTemplateHandler()
+	g.initializeApiKeyHandler(apiKeyUseCase)
+	g.initializeRoleHandler()
+	g.initializeAuditHandler()
+	g.initializeUsageHandler()
+	g.initializeDownloadHandler()
+	g.initializeDocs()
+}
+
+// initializeDocs serves the OpenAPI document. It must run after every other
+// route is registered: in debug mode it checks the document against the
+// routes and stops the server on a mismatch, so it is noticed during
+// development. TestOpenAPIMatchesRoutes enforces the same in CI.
+func (g *ginServer) initializeDocs() {
+	g.app.GET("/openapi.json", openapi.ServeSpec)
+	g.app.GET("/docs", openapi.ServeDocs)
+	g.app.GET("/docs/assets/:name", openapi.ServeDocsAsset)
 
-	g.log.GetLogger().Info("Server started on port " + strconv.Itoa(g.conf.Server.Port))
-	g.app.Run(":" + strconv.Itoa(g.conf.Server.Port))
+	if gin.Mode() != gin.DebugMode {
+		return
+	}
+	problems, err := openapi.CheckRoutes(g.app.Routes())
+	if err != nil {
+		g.log.GetLogger().WithError(err).Fatal("Failed to check the OpenAPI document")
+	}
+	for _, problem := range problems {
+		g.log.GetLogger().WithField("problem", problem).Error("OpenAPI document does not match the routes")
+	}
+	if len(problems) > 0 {
+		g.log.GetLogger().Fatal("OpenAPI document does not match the routes, update openapi/openapi.json")
+	}
+}
+
+// goWorker runs fn in the background until the server shuts down.
+func (g *ginServer) goWorker(fn func(ctx context.Context)) {
+	g.workers.Add(1)
+	go func() {
+		defer g.workers.Done()
+		fn(g.ctx)
+	}()
+}
+
+func (g *ginServer) initializeHealthHandler() {
+	timeout := 5 * time.Second
+	selfTestInterval := 5 * time.Minute
+	if g.conf.Health != nil {
+		if g.conf.Health.Timeout > 0 {
+			timeout = g.conf.Health.Timeout
+		}
+		if g.conf.Health.SelfTestInterval > 0 {
+			selfTestInterval = g.conf.Health.SelfTestInterval
+		}
+	}
+
+	g.health = health.NewChecker(timeout)
+	g.health.Register("database", health.Database(g.db))
+	g.health.Register("storage", health.Storage(g.storage))
+	// A conversion takes seconds, so it runs in the background rather than
+	// on every probe.
+	g.health.Register("libreoffice", health.Periodic(g.ctx, health.LibreOffice(), selfTestInterval, 2*time.Minute))
+
+	healthHandler := handler.NewHealthHandler(g.health, g.log)
+	g.app.GET("/livez", healthHandler.Livez)
+	g.app.GET("/health", healthHandler.Livez)
+	g.app.GET("/readyz", healthHandler.Readyz)
+}
+
+// runTemplatePurge periodically purges templates whose trash retention has
+// expired, across all tenants. It runs until ctx is done.
+func (g *ginServer) runTemplatePurge(ctx context.Context, templateUseCase usecase.ITemplateUseCase) {
+	if g.conf.Storage == nil || g.conf.Storage.PurgeInterval <= 0 {
+		g.log.GetLogger().Warn("Template purge scheduler disabled")
+		return
+	}
+
+	ticker := time.NewTicker(g.conf.Storage.PurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Every run is a trace and a job of its own, there is no request to
+		// join. A run in progress finishes even when shutdown begins.
+		jobCtx := logger.WithField(tenant.WithAllTenants(context.Background()), logger.FieldJobID, uuid.NewString())
+		jobCtx, span := tracing.Start(jobCtx, "TemplatePurge")
+		purged, err := templateUseCase.PurgeExpiredTemplates(jobCtx, g.conf.Storage.TrashRetention)
+		if err != nil {
+			tracing.Fail(span, err)
+			g.log.WithContext(jobCtx).WithError(err).Error("Failed to purge expired templates")
+		}
+		span.End()
+		if purged > 0 {
+			g.log.WithContext(jobCtx).WithField("purged", purged).Info("Purged expired templates")
+		}
+	}
+}
+
+// authMiddleware returns the middleware guarding /api/v1. Authentication can
+// only be switched off explicitly through auth.enabled.
+func (g *ginServer) authMiddleware(apiKeys auth.ApiKeyVerifier) []gin.HandlerFunc {
+	if g.conf.Auth.Disabled() {
+		g.log.GetLogger().WithField("tenant", tenant.Default).Warn("Authentication disabled, /api/v1 is open and acts for the default tenant")
+		return []gin.HandlerFunc{middleware.ResolveTenant(), middleware.RecordActor()}
+	}
+
+	var jwtConf *config.JWT
+	if g.conf.Auth != nil {
+		jwtConf = g.conf.Auth.Jwt
+	}
+	verifier, err := auth.NewJWTVerifier(jwtConf)
+	if err != nil {
+		g.log.GetLogger().WithError(err).Fatal("Failed to configure JWT authentication")
+	}
+	return []gin.HandlerFunc{middleware.Authenticate(verifier, apiKeys, g.log), middleware.ResolveTenant(), middleware.RecordActor()}
+}
+
+// renderLimits returns the rate limit middleware for render routes.
+func (g *ginServer) renderLimits() []gin.HandlerFunc {
+	if g.conf.RateLimit == nil || !g.conf.RateLimit.Enabled {
+		g.log.GetLogger().Warn("Rate limiting disabled")
+		return nil
+	}
+
+	limiter, err := ratelimit.NewLimiter(&g.conf, g.db)
+	if err != nil {
+		g.log.GetLogger().WithError(err).Fatal("Failed to configure rate limiting")
+	}
+	if sweeper, ok := limiter.(ratelimit.Sweeper); ok {
+		g.goWorker(func(ctx context.Context) {
+			g.runRateLimitSweep(ctx, sweeper)
+		})
+	}
+	return []gin.HandlerFunc{middleware.RateLimit(limiter, g.log)}
+}
+
+// renderQuota returns the quota middleware for renders that are delivered.
+func (g *ginServer) renderQuota() gin.HandlerFunc {
+	quotaUseCase := usecase.NewQuotaUseCase(repository.NewQuotaRepository(g.db, g.log), &g.conf)
+	return middleware.RenderQuota(quotaUseCase, g.log)
+}
+
+// runRateLimitSweep periodically deletes idle rate limit buckets kept in the
+// database. It runs until ctx is done.
+func (g *ginServer) runRateLimitSweep(ctx context.Context, sweeper ratelimit.Sweeper) {
+	ticker := time.NewTicker(ratelimit.SweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		jobCtx := logger.WithField(context.Background(), logger.FieldJobID, uuid.NewString())
+		jobCtx, span := tracing.Start(jobCtx, "RateLimitSweep")
+		swept, err := sweeper.Sweep(jobCtx)
+		if err != nil {
+			tracing.Fail(span, err)
+			g.log.WithContext(jobCtx).WithError(err).Error("Failed to sweep rate limit buckets")
+		}
+		span.End()
+		if swept > 0 {
+			g.log.WithContext(jobCtx).WithField("swept", swept).Debug("Swept idle rate limit buckets")
+		}
+	}
+}
+
+// can guards a route with a permission in the caller's tenant.
+func (g *ginServer) can(permission rbac.Permission) gin.HandlerFunc {
+	return middleware.RequirePermission(g.roles, permission, g.log)
 }
 
 func (g *ginServer) GetApp() *gin.Engine {
@@ -79,14 +341,81 @@ func (g *ginServer) GetApp() *gin.Engine {
 
 func (g *ginServer) initializeTemplateHandler() {
 	templateRepository := repository.NewTemplateRepository(g.db, g.log)
-	templateDTO := dto.NewTemplateDTO(g.conf, g.log)
-	templateUseCase := usecase.NewTemplateUseCase(templateRepository, templateDTO)
-	templateHandler := handler.NewTemplateHandler(templateUseCase, g.log, g.validator, g.conf)
-
-	templateRoutes := g.app.Group("/api/v1/templates/")
-	templateRoutes.POST("store", templateHandler.CreateTemplate)
-	templateRoutes.POST("generate-pdf", templateHandler.GeneratePDF)
-	templateRoutes.GET("", templateHandler.FindAllTemplate)
-	templateRoutes.GET(":id", templateHandler.FindTemplateByID)
-	templateRoutes.DELETE(":id", templateHandler.DeleteTemplateByID)
+	templateDTO := dto.NewTemplateDTO(g.conf, g.log, g.signer)
+	templateUseCase := usecase.NewTemplateUseCase(templateRepository, repository.NewAuditRepository(g.db, g.log), templateDTO, g.storage, g.signer, &g.conf)
+	templateHandler := handler.NewTemplateHandler(templateUseCase, g.usage, g.log, g.validator, g.conf, g.storage, g.signer, upload.NewGuard(&g.conf))
+
+	templateRoutes := g.api.Group("/templates/", middleware.LogParam("id", logger.FieldTemplateID))
+	templateRoutes.POST("store", g.can(rbac.PermissionTemplateUpload), templateHandler.CreateTemplate)
+	templateRoutes.GET("", g.can(rbac.PermissionTemplateView), templateHandler.FindAllTemplate)
+	templateRoutes.GET("search", g.can(rbac.PermissionTemplateView), templateHandler.SearchTemplates)
+	templateRoutes.GET("types", g.can(rbac.PermissionTemplateView), templateHandler.FindTemplateTypes)
+	templateRoutes.POST("purge", g.can(rbac.PermissionTemplateDelete), templateHandler.PurgeExpiredTemplates)
+	templateRoutes.GET(":id", g.can(rbac.PermissionTemplateView), templateHandler.FindTemplateByID)
+	templateRoutes.PUT(":id", g.can(rbac.PermissionTemplateUpload), templateHandler.UpdateTemplateMetadata)
+	templateRoutes.DELETE(":id", g.can(rbac.PermissionTemplateDelete), templateHandler.DeleteTemplateByID)
+	templateRoutes.PUT(":id/file", g.can(rbac.PermissionTemplateUpload), templateHandler.ReplaceTemplateFile)
+	templateRoutes.GET(":id/versions", g.can(rbac.PermissionTemplateView), templateHandler.FindTemplateVersions)
+	templateRoutes.POST(":id/rollback", g.can(rbac.PermissionTemplateUpload), templateHandler.RollbackTemplate)
+	templateRoutes.POST(":id/submit", g.can(rbac.PermissionTemplateUpload), templateHandler.SubmitTemplate)
+	templateRoutes.POST(":id/approve", g.can(rbac.PermissionTemplatePublish), templateHandler.ApproveTemplate)
+	templateRoutes.POST(":id/reject", g.can(rbac.PermissionTemplatePublish), templateHandler.RejectTemplate)
+	templateRoutes.POST(":id/archive", g.can(rbac.PermissionTemplatePublish), templateHandler.ArchiveTemplate)
+	templateRoutes.POST(":id/restore", g.can(rbac.PermissionTemplateDelete), templateHandler.RestoreTemplateByID)
+	templateRoutes.DELETE(":id/purge", g.can(rbac.PermissionTemplateDelete), templateHandler.PurgeTemplateByID)
+	templateRoutes.GET(":id/download-url", g.can(rbac.PermissionTemplateView), templateHandler.CreateDownloadURL)
+
+	// Renders start LibreOffice, so they are throttled per client. Only
+	// delivered documents count against the tenant's quota; previews do not.
+	renderRoutes := templateRoutes.Group("", append([]gin.HandlerFunc{g.can(rbac.PermissionTemplateRender)}, g.renderLimits()...)...)
+	renderRoutes.POST("generate-pdf", g.renderQuota(), templateHandler.GeneratePDF)
+	renderRoutes.POST("preview", templateHandler.PreviewPDF)
+
+	g.goWorker(func(ctx context.Context) {
+		g.runTemplatePurge(ctx, templateUseCase)
+	})
+}
+
+func (g *ginServer) initializeApiKeyHandler(apiKeyUseCase usecase.IApiKeyUseCase) {
+	apiKeyHandler := handler.NewApiKeyHandler(apiKeyUseCase, g.log, g.validator)
+
+	apiKeyRoutes := g.api.Group("/api-keys/", g.can(rbac.PermissionApiKeyManage))
+	apiKeyRoutes.POST("", apiKeyHandler.CreateApiKey)
+	apiKeyRoutes.GET("", apiKeyHandler.FindAllApiKey)
+	apiKeyRoutes.POST(":id/rotate", apiKeyHandler.RotateApiKey)
+	apiKeyRoutes.DELETE(":id", apiKeyHandler.RevokeApiKey)
+}
+
+func (g *ginServer) initializeRoleHandler() {
+	roleHandler := handler.NewRoleHandler(g.roles, g.log, g.validator)
+
+	roleRoutes := g.api.Group("/roles/", g.can(rbac.PermissionRoleManage))
+	roleRoutes.GET("", roleHandler.FindRoles)
+	roleRoutes.GET("assignments", roleHandler.FindRoleAssignments)
+	roleRoutes.POST("assignments", roleHandler.AssignRole)
+	roleRoutes.DELETE("assignments/:id", roleHandler.RevokeRole)
+}
+
+func (g *ginServer) initializeAuditHandler() {
+	auditUseCase := usecase.NewAuditUseCase(repository.NewAuditRepository(g.db, g.log))
+	auditHandler := handler.NewAuditHandler(auditUseCase, g.log, g.validator)
+
+	auditRoutes := g.api.Group("/audit-logs/", g.can(rbac.PermissionAuditView))
+	auditRoutes.GET("", auditHandler.FindAuditLogs)
+	auditRoutes.GET("export", auditHandler.ExportAuditLogs)
+}
+
+func (g *ginServer) initializeUsageHandler() {
+	usageHandler := handler.NewUsageHandler(g.usage, g.log, g.validator)
+
+	usageRoutes := g.api.Group("/usage/", g.can(rbac.PermissionUsageView))
+	usageRoutes.GET("", usageHandler.FindUsage)
+}
+
+func (g *ginServer) initializeDownloadHandler() {
+	downloadRepository := repository.NewDownloadRepository(g.db, g.log)
+	downloadUseCase := usecase.NewDownloadUseCase(downloadRepository, g.signer, g.storage)
+	downloadHandler := handler.NewDownloadHandler(downloadUseCase, g.log, g.validator)
+
+	g.app.GET("/download", downloadHandler.Download)
 }
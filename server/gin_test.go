@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/openapi"
+	"github.com/IlhamSetiaji/report-converter/signer"
+	"github.com/IlhamSetiaji/report-converter/storage"
+	"github.com/IlhamSetiaji/report-converter/validator"
+	"github.com/gin-gonic/gin"
+)
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authEnabled := false
+	conf := config.Config{
+		Server:   &config.Server{Name: "report-converter", Url: "http://localhost"},
+		Auth:     &config.Auth{Enabled: &authEnabled},
+		Download: &config.Download{SigningKey: "0123456789abcdef0123456789abcdef", Expiry: time.Minute},
+		Audit:    &config.Audit{HashKey: "fedcba9876543210fedcba9876543210"},
+		Metrics:  &config.Metrics{Enabled: true},
+	}
+	log := logger.NewLogger(&conf)
+	srv := NewGinServer(nil, storage.NewLocalStorage(t.TempDir()), signer.NewHMACSigner(&conf), conf, log, validator.NewValidatorV10(&conf)).(*ginServer)
+
+	// Registering routes starts background workers; a cancelled context
+	// stops them straight away.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	srv.ctx = ctx
+	srv.registerRoutes()
+	srv.workers.Wait()
+
+	problems, err := openapi.CheckRoutes(srv.GetApp().Routes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, problem := range problems {
+		t.Error(problem)
+	}
+}
@@ -0,0 +1,98 @@
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid download signature")
+	ErrExpired          = errors.New("download link has expired")
+)
+
+// SignedURL carries the query parameters of a signed download link.
+type SignedURL struct {
+	Key       string
+	Expires   int64
+	Nonce     string
+	Signature string
+}
+
+// URLSigner creates and verifies expiring download links for stored objects.
+// Single-use links carry a random nonce; the caller is responsible for
+// remembering which nonces have already been consumed.
+type URLSigner interface {
+	Sign(key string, ttl time.Duration, singleUse bool) (string, time.Time, error)
+	Verify(u *SignedURL) error
+}
+
+type hmacSigner struct {
+	secret  []byte
+	baseUrl string
+}
+
+// NewHMACSigner signs links with HMAC-SHA256 using download.signingkey. Links
+// point at the /download route of server.url. It panics if the key is
+// missing, a placeholder or shorter than config.MinSecretLength.
+func NewHMACSigner(conf *config.Config) URLSigner {
+	var key string
+	if conf.Download != nil {
+		key = conf.Download.SigningKey
+	}
+	if err := config.CheckSecret("download.signingkey", key); err != nil {
+		panic(err.Error())
+	}
+
+	return &hmacSigner{
+		secret:  []byte(conf.Download.SigningKey),
+		baseUrl: conf.Server.Url + "/download",
+	}
+}
+
+func (s *hmacSigner) Sign(key string, ttl time.Duration, singleUse bool) (string, time.Time, error) {
+	expiresAt := time.Now().Add(ttl)
+	u := &SignedURL{Key: key, Expires: expiresAt.Unix()}
+	if singleUse {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return "", time.Time{}, err
+		}
+		u.Nonce = hex.EncodeToString(nonce)
+	}
+	u.Signature = s.signature(u)
+
+	query := url.Values{}
+	query.Set("key", u.Key)
+	query.Set("expires", strconv.FormatInt(u.Expires, 10))
+	if u.Nonce != "" {
+		query.Set("nonce", u.Nonce)
+	}
+	query.Set("signature", u.Signature)
+	return s.baseUrl + "?" + query.Encode(), expiresAt, nil
+}
+
+func (s *hmacSigner) Verify(u *SignedURL) error {
+	expected := s.signature(u)
+	if !hmac.Equal([]byte(expected), []byte(u.Signature)) {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > u.Expires {
+		return ErrExpired
+	}
+	return nil
+}
+
+func (s *hmacSigner) signature(u *SignedURL) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(u.Key + "\n" + strconv.FormatInt(u.Expires, 10) + "\n" + u.Nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,112 @@
+package signer
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+)
+
+func newTestSigner() URLSigner {
+	return NewHMACSigner(&config.Config{
+		Server:   &config.Server{Url: "https://reports.example.com"},
+		Download: &config.Download{SigningKey: "0123456789abcdef0123456789abcdef"},
+	})
+}
+
+// parse reads a signed link back the way the download handler does.
+func parse(t *testing.T, link string) *SignedURL {
+	t.Helper()
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != "/download" {
+		t.Fatalf("link points at %s", u.Path)
+	}
+	query := u.Query()
+	expires, _ := strconv.ParseInt(query.Get("expires"), 10, 64)
+	return &SignedURL{
+		Key:       query.Get("key"),
+		Expires:   expires,
+		Nonce:     query.Get("nonce"),
+		Signature: query.Get("signature"),
+	}
+}
+
+func TestSignedLinkVerifies(t *testing.T) {
+	s := newTestSigner()
+	key := "storage/tenants/acme/generated/out.pdf"
+
+	link, expiresAt, err := s.Sign(key, time.Minute, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := parse(t, link)
+	if signed.Key != key || signed.Expires != expiresAt.Unix() || signed.Nonce != "" {
+		t.Fatalf("link carries %+v", signed)
+	}
+	if err := s.Verify(signed); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	link, _, err = s.Sign(key, time.Minute, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed := parse(t, link); signed.Nonce == "" || s.Verify(signed) != nil {
+		t.Fatalf("single-use link %+v does not verify", signed)
+	}
+}
+
+func TestSignedLinkRejectsTampering(t *testing.T) {
+	s := newTestSigner()
+	link, _, err := s.Sign("storage/tenants/acme/generated/out.pdf", time.Minute, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := []func(u *SignedURL){
+		func(u *SignedURL) { u.Key = "storage/tenants/globex/generated/out.pdf" },
+		func(u *SignedURL) { u.Expires += 3600 },
+		func(u *SignedURL) { u.Nonce = "" },
+		func(u *SignedURL) { u.Signature = u.Signature[1:] },
+	}
+	for i, tamper := range tampered {
+		signed := parse(t, link)
+		tamper(signed)
+		if err := s.Verify(signed); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("tampering %d: err = %v, want ErrInvalidSignature", i, err)
+		}
+	}
+}
+
+func TestSignedLinkExpires(t *testing.T) {
+	s := newTestSigner()
+	link, _, err := s.Sign("storage/generated/out.pdf", -time.Minute, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Verify(parse(t, link)); !errors.Is(err, ErrExpired) {
+		t.Fatalf("err = %v, want ErrExpired", err)
+	}
+}
+
+func TestWeakSigningKeyIsRefused(t *testing.T) {
+	for _, key := range []string{"", "change_me_to_a_long_random_secret", "short-key"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewHMACSigner accepted signing key %q", key)
+				}
+			}()
+			NewHMACSigner(&config.Config{
+				Server:   &config.Server{Url: "https://reports.example.com"},
+				Download: &config.Download{SigningKey: key},
+			})
+		}()
+	}
+}
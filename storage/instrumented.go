@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/IlhamSetiaji/report-converter/metrics"
+	"github.com/IlhamSetiaji/report-converter/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentedStorage traces every operation and counts backend errors per
+// operation. A missing object is an expected answer rather than a failure and
+// is neither counted nor marked as failed.
+type instrumentedStorage struct {
+	driver string
+	next   Storage
+}
+
+func instrument(driver string, next Storage) Storage {
+	return &instrumentedStorage{driver: driver, next: next}
+}
+
+func (s *instrumentedStorage) start(ctx context.Context, operation string, key string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "storage."+operation, trace.WithAttributes(
+		attribute.String("storage.driver", s.driver),
+		attribute.String("storage.key", key),
+	))
+}
+
+func (s *instrumentedStorage) observe(span trace.Span, operation string, err error) error {
+	defer span.End()
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		metrics.StorageErrors.WithLabelValues(s.driver, operation).Inc()
+		tracing.Fail(span, err)
+	}
+	return err
+}
+
+func (s *instrumentedStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	ctx, span := s.start(ctx, "put", key)
+	return s.observe(span, "put", s.next.Put(ctx, key, r, size, contentType))
+}
+
+func (s *instrumentedStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	ctx, span := s.start(ctx, "get", key)
+	rc, err := s.next.Get(ctx, key)
+	return rc, s.observe(span, "get", err)
+}
+
+func (s *instrumentedStorage) Delete(ctx context.Context, key string) error {
+	ctx, span := s.start(ctx, "delete", key)
+	return s.observe(span, "delete", s.next.Delete(ctx, key))
+}
+
+func (s *instrumentedStorage) Move(ctx context.Context, src string, dst string) error {
+	ctx, span := s.start(ctx, "move", src)
+	span.SetAttributes(attribute.String("storage.destination", dst))
+	return s.observe(span, "move", s.next.Move(ctx, src, dst))
+}
+
+func (s *instrumentedStorage) Exists(ctx context.Context, key string) (bool, error) {
+	ctx, span := s.start(ctx, "exists", key)
+	ok, err := s.next.Exists(ctx, key)
+	return ok, s.observe(span, "exists", err)
+}
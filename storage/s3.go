@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type s3Storage struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3Storage stores objects in an S3-compatible bucket such as AWS S3 or
+// MinIO. The bucket is created when it does not exist yet.
+func NewS3Storage(conf *config.S3) (Storage, error) {
+	if conf == nil {
+		return nil, errors.New("storage: s3 driver selected without storage.s3 configuration")
+	}
+
+	client, err := minio.New(conf.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(conf.AccessKey, conf.SecretKey, ""),
+		Secure: conf.UseSSL,
+		Region: conf.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to create s3 client: %w", err)
+	}
+
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, conf.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to check bucket %s: %w", conf.Bucket, err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, conf.Bucket, minio.MakeBucketOptions{Region: conf.Region}); err != nil {
+			return nil, fmt.Errorf("storage: failed to create bucket %s: %w", conf.Bucket, err)
+		}
+	}
+
+	return &s3Storage{client: client, bucket: conf.Bucket}, nil
+}
+
+func (s *s3Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *s3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// GetObject is lazy, so stat first to report missing objects up front.
+	if _, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{}); err != nil {
+		return nil, translateS3Error(err)
+	}
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, translateS3Error(err)
+	}
+	return obj, nil
+}
+
+func (s *s3Storage) Delete(ctx context.Context, key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *s3Storage) Move(ctx context.Context, src string, dst string) error {
+	src, err := cleanKey(src)
+	if err != nil {
+		return err
+	}
+	dst, err = cleanKey(dst)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: s.bucket, Object: dst},
+		minio.CopySrcOptions{Bucket: s.bucket, Object: src},
+	)
+	if err != nil {
+		return translateS3Error(err)
+	}
+	return s.client.RemoveObject(ctx, s.bucket, src, minio.RemoveObjectOptions{})
+}
+
+func (s *s3Storage) Exists(ctx context.Context, key string) (bool, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return false, err
+	}
+	_, err = s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if errors.Is(translateS3Error(err), ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func translateS3Error(err error) error {
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NoSuchObject", "NotFound":
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+)
+
+// ErrNotFound is returned when an object does not exist.
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage is the persistence layer for template files and generated outputs.
+// Objects are addressed by slash separated keys such as
+// "storage/templates/<name>"; the same keys work for every backend.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	Move(ctx context.Context, src string, dst string) error
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// NewStorage builds the backend selected by storage.driver. An empty driver
+// selects the local filesystem.
+func NewStorage(conf *config.Config) Storage {
+	if conf.Storage == nil {
+		return instrument("local", NewLocalStorage("."))
+	}
+
+	switch conf.Storage.Driver {
+	case "", "local":
+		root := conf.Storage.LocalRoot
+		if root == "" {
+			root = "."
+		}
+		return instrument("local", NewLocalStorage(root))
+	case "s3":
+		s, err := NewS3Storage(conf.Storage.S3)
+		if err != nil {
+			panic(err)
+		}
+		return instrument("s3", s)
+	default:
+		panic(fmt.Sprintf("unknown storage driver %q", conf.Storage.Driver))
+	}
+}
+
+// ReadAll returns the full contents of an object.
+func ReadAll(ctx context.Context, s Storage, key string) ([]byte, error) {
+	rc, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// PutBytes stores data under key.
+func PutBytes(ctx context.Context, s Storage, key string, data []byte, contentType string) error {
+	return s.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType)
+}
+
+// cleanKey normalises a key and rejects keys that would escape the storage
+// root, such as absolute paths or paths containing "..".
+func cleanKey(key string) (string, error) {
+	key = strings.ReplaceAll(key, "\\", "/")
+	if strings.HasPrefix(key, "/") {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("storage: invalid key %q", key)
+		}
+	}
+
+	cleaned := path.Clean(key)
+	if cleaned == "." {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/IlhamSetiaji/report-converter/tenant"
+)
+
+// testBackend runs the behaviour every backend must share against s.
+func testBackend(t *testing.T, s Storage) {
+	ctx := context.Background()
+	key := "storage/templates/report.docx"
+	content := []byte("template body")
+
+	if err := PutBytes(ctx, s, key, content, "application/octet-stream"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if exists, err := s.Exists(ctx, key); err != nil || !exists {
+		t.Fatalf("exists after put = %v, %v", exists, err)
+	}
+	got, err := ReadAll(ctx, s, key)
+	if err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("get = %q, %v, want %q", got, err, content)
+	}
+
+	// Put replaces an existing object.
+	if err := PutBytes(ctx, s, key, []byte("v2"), "application/octet-stream"); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	if got, _ := ReadAll(ctx, s, key); string(got) != "v2" {
+		t.Fatalf("get after overwrite = %q", got)
+	}
+
+	trashed := "storage/trash/templates/report.docx"
+	if err := s.Move(ctx, key, trashed); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get moved source: err = %v, want ErrNotFound", err)
+	}
+	if got, _ := ReadAll(ctx, s, trashed); string(got) != "v2" {
+		t.Fatalf("get moved destination = %q", got)
+	}
+	if err := s.Move(ctx, key, trashed); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("move missing source: err = %v, want ErrNotFound", err)
+	}
+
+	if err := s.Delete(ctx, trashed); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if exists, err := s.Exists(ctx, trashed); err != nil || exists {
+		t.Fatalf("exists after delete = %v, %v", exists, err)
+	}
+	if err := s.Delete(ctx, trashed); err != nil {
+		t.Fatalf("delete missing object: %v", err)
+	}
+
+	for _, invalid := range []string{"../outside", "/etc/passwd", "storage/../../outside", ""} {
+		if err := PutBytes(ctx, s, invalid, content, "text/plain"); err == nil {
+			t.Errorf("put accepted invalid key %q", invalid)
+		}
+		if _, err := s.Get(ctx, invalid); err == nil {
+			t.Errorf("get accepted invalid key %q", invalid)
+		}
+	}
+
+	// Keys are prefixed per tenant, so one tenant's object is invisible under
+	// another tenant's key for the same name.
+	acme := tenant.WithID(ctx, "acme")
+	globex := tenant.WithID(ctx, "globex")
+	acmeKey := tenant.StorageKey(acme, "storage/generated/out.pdf")
+	if acmeKey != "storage/tenants/acme/generated/out.pdf" {
+		t.Fatalf("tenant key = %q", acmeKey)
+	}
+	if err := PutBytes(ctx, s, acmeKey, content, "application/pdf"); err != nil {
+		t.Fatalf("put tenant object: %v", err)
+	}
+	if exists, err := s.Exists(ctx, tenant.StorageKey(globex, "storage/generated/out.pdf")); err != nil || exists {
+		t.Fatalf("other tenant sees the object: %v, %v", exists, err)
+	}
+	if exists, err := s.Exists(ctx, acmeKey); err != nil || !exists {
+		t.Fatalf("owning tenant misses the object: %v, %v", exists, err)
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	testBackend(t, NewLocalStorage(t.TempDir()))
+}
+
+func TestS3Storage(t *testing.T) {
+	server := httptest.NewServer(newFakeS3())
+	defer server.Close()
+
+	endpoint, _ := url.Parse(server.URL)
+	s, err := NewS3Storage(&config.S3{
+		Endpoint:  endpoint.Host,
+		AccessKey: "access",
+		SecretKey: "secret",
+		Bucket:    "templates",
+		Region:    "us-east-1",
+	})
+	if err != nil {
+		t.Fatalf("new s3 storage: %v", err)
+	}
+	testBackend(t, s)
+}
+
+// fakeS3 is an in-memory stand-in for the part of the S3 API the backend
+// uses, with path-style addressing. Requests are not authenticated.
+type fakeS3 struct {
+	mu      sync.Mutex
+	buckets map[string]bool
+	objects map[string][]byte
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{buckets: map[string]bool{}, objects: map[string][]byte{}}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, object, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if object == "" {
+		switch r.Method {
+		case http.MethodHead:
+			if !f.buckets[bucket] {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case http.MethodPut:
+			f.buckets[bucket] = true
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+		return
+	}
+
+	name := bucket + "/" + object
+	switch r.Method {
+	case http.MethodPut:
+		if source := r.Header.Get("X-Amz-Copy-Source"); source != "" {
+			source, _ = url.PathUnescape(strings.TrimPrefix(source, "/"))
+			data, ok := f.objects[source]
+			if !ok {
+				writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+				return
+			}
+			f.objects[name] = data
+			fmt.Fprintf(w, `<CopyObjectResult><ETag>"etag"</ETag><LastModified>%s</LastModified></CopyObjectResult>`,
+				time.Now().UTC().Format(time.RFC3339))
+			return
+		}
+		data, err := readS3Body(r)
+		if err != nil {
+			writeS3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.objects[name] = data
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodHead, http.MethodGet:
+		data, ok := f.objects[name]
+		if !ok {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodDelete:
+		delete(f.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// readS3Body returns the payload of an upload, decoding the aws-chunked
+// encoding the client uses over plain HTTP.
+func readS3Body(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var data []byte
+	body := bufio.NewReader(r.Body)
+	for {
+		line, err := body.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data, nil
+		}
+		chunk := make([]byte, size+2)
+		if _, err := io.ReadFull(body, chunk); err != nil {
+			return nil, err
+		}
+		data = append(data, chunk[:size]...)
+	}
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
@@ -0,0 +1,72 @@
+// Package tenant carries the tenant a request acts for through
+// context.Context, so repositories can scope every query to it.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Default is the tenant used when authentication is disabled and the tenant
+// that rows created before multi-tenancy belong to.
+const Default = "default"
+
+// ErrMissing is returned by repositories when a query runs without a tenant.
+var ErrMissing = errors.New("no tenant in context")
+
+// ErrInvalid is returned for tenant IDs that are unsafe to use in storage keys.
+var ErrInvalid = errors.New("invalid tenant ID")
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type contextKey int
+
+const (
+	idKey contextKey = iota
+	allKey
+)
+
+// Validate reports whether id can be used as a tenant ID.
+func Validate(id string) error {
+	if !validID.MatchString(id) {
+		return ErrInvalid
+	}
+	return nil
+}
+
+// WithID returns a context acting for the given tenant.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, idKey, id)
+}
+
+// FromContext returns the tenant of ctx, or "" when there is none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(idKey).(string)
+	return id
+}
+
+// WithAllTenants returns a context that is allowed to see every tenant. It
+// is meant for background jobs and for resolving credentials before the
+// tenant is known, never for request handling.
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allKey, true)
+}
+
+// IsAllTenants reports whether ctx was created by WithAllTenants.
+func IsAllTenants(ctx context.Context) bool {
+	all, _ := ctx.Value(allKey).(bool)
+	return all
+}
+
+// StorageKey places a storage key below the tenant's prefix, turning
+// "storage/templates/a.docx" into "storage/tenants/<id>/templates/a.docx".
+func StorageKey(ctx context.Context, key string) string {
+	id := FromContext(ctx)
+	if id == "" {
+		id = Default
+	}
+	return path.Join("storage/tenants", id, strings.TrimPrefix(key, "storage/"))
+}
@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "tracing:span"
+
+// GormPlugin creates a client span for every query GORM runs, as a child of
+// the span in the statement context. Queries only join the caller's trace
+// when they are built with WithContext.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	registrations := []error{
+		cb.Create().Before("gorm:create").Register("tracing:before_create", startQuerySpan("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", endQuerySpan),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", startQuerySpan("query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", endQuerySpan),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", startQuerySpan("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", endQuerySpan),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", startQuerySpan("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", endQuerySpan),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", startQuerySpan("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", endQuerySpan),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", startQuerySpan("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", endQuerySpan),
+	}
+	return errors.Join(registrations...)
+}
+
+func startQuerySpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement == nil || db.Statement.Context == nil {
+			return
+		}
+		ctx, span := Start(db.Statement.Context, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBOperationName(operation)),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func endQuerySpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBCollectionName(db.Statement.Table),
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		Fail(span, db.Error)
+	}
+}
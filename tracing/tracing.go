@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/IlhamSetiaji/report-converter"
+
+// Setup installs the global tracer provider and propagator selected by the
+// tracing section of the configuration. The returned shutdown flushes
+// buffered spans and must be called before the process exits. When tracing is
+// disabled spans are still created, but never recorded or exported.
+func Setup(conf *config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	noop := func(context.Context) error { return nil }
+	if conf.Tracing == nil || !conf.Tracing.Enabled {
+		return noop, nil
+	}
+
+	exporter, closeExporter, err := newExporter(conf.Tracing)
+	if err != nil {
+		return noop, err
+	}
+
+	serviceName := conf.Tracing.ServiceName
+	if serviceName == "" {
+		serviceName = "report-converter"
+	}
+	ratio := conf.Tracing.SampleRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		closeExporter()
+		return err
+	}, nil
+}
+
+// newExporter builds the span exporter for tracing.exporter: "otlp" sends
+// spans over OTLP/HTTP, "stdout" and "file" write them as JSON for local use.
+func newExporter(conf *config.Tracing) (sdktrace.SpanExporter, func(), error) {
+	switch conf.Exporter {
+	case "", "otlp":
+		var opts []otlptracehttp.Option
+		if conf.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(conf.Endpoint))
+		}
+		exporter, err := otlptracehttp.New(context.Background(), opts...)
+		return exporter, func() {}, err
+	case "stdout":
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		return exporter, func() {}, err
+	case "file":
+		f, err := os.OpenFile(conf.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("tracing: open %q: %w", conf.File, err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return exporter, func() { f.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("tracing: unknown exporter %q", conf.Exporter)
+	}
+}
+
+// Tracer returns the tracer used for the spans of this service.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start starts a span named name as a child of the span in ctx.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// Fail records err on span and marks the span as failed.
+func Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
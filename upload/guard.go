@@ -0,0 +1,249 @@
+package upload
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/IlhamSetiaji/report-converter/entity"
+	"github.com/IlhamSetiaji/report-converter/utils"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+var (
+	ErrTooLarge     = errors.New("file exceeds the maximum upload size")
+	ErrTypeMismatch = errors.New("file content does not match the declared template type")
+	ErrUnsafe       = errors.New("file contains unsafe content")
+)
+
+// Inspection is the result of a successful check.
+type Inspection struct {
+	FileName string
+	MimeType string
+}
+
+// Guard vets uploaded template files before they are stored.
+type Guard interface {
+	MaxSize() int64
+	Inspect(fileName string, declaredType string, data []byte) (*Inspection, error)
+}
+
+type guard struct {
+	maxSize             int64
+	maxEntries          int
+	maxUncompressedSize uint64
+	maxCompressionRatio uint64
+}
+
+// NewGuard builds a guard from the upload section of the config, falling back
+// to conservative defaults for unset limits.
+func NewGuard(conf *config.Config) Guard {
+	g := &guard{
+		maxSize:             10 << 20,
+		maxEntries:          1000,
+		maxUncompressedSize: 100 << 20,
+		maxCompressionRatio: 100,
+	}
+	if conf.Upload != nil {
+		if conf.Upload.MaxSize > 0 {
+			g.maxSize = conf.Upload.MaxSize
+		}
+		if conf.Upload.MaxEntries > 0 {
+			g.maxEntries = conf.Upload.MaxEntries
+		}
+		if conf.Upload.MaxUncompressedSize > 0 {
+			g.maxUncompressedSize = uint64(conf.Upload.MaxUncompressedSize)
+		}
+		if conf.Upload.MaxCompressionRatio > 0 {
+			g.maxCompressionRatio = uint64(conf.Upload.MaxCompressionRatio)
+		}
+	}
+	return g
+}
+
+func (g *guard) MaxSize() int64 {
+	return g.maxSize
+}
+
+// Inspect checks the size, sniffs the real content type and makes sure it
+// agrees with the declared template type, and for OOXML packages rejects zip
+// bombs, macros, embedded OLE objects and external relationships. It returns
+// a sanitised file name that is safe to use in a storage key.
+func (g *guard) Inspect(fileName string, declaredType string, data []byte) (*Inspection, error) {
+	if int64(len(data)) > g.maxSize {
+		return nil, ErrTooLarge
+	}
+
+	spec, ok := entity.LookupTemplateType(declaredType)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown template type %q", ErrTypeMismatch, declaredType)
+	}
+
+	detected := mimetype.Detect(data)
+	if !mimeAllowed(detected, spec.MimeTypes) {
+		return nil, fmt.Errorf("%w: detected %s", ErrTypeMismatch, detected.String())
+	}
+
+	name := SanitizeFileName(fileName)
+	if !strings.EqualFold(filepath.Ext(name), spec.Extension) {
+		name = strings.TrimSuffix(name, filepath.Ext(name)) + spec.Extension
+	}
+
+	if spec.Package {
+		if err := g.inspectOOXML(data); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Inspection{FileName: name, MimeType: detected.String()}, nil
+}
+
+func mimeAllowed(detected *mimetype.MIME, allowed []string) bool {
+	for _, mime := range allowed {
+		if detected.Is(mime) {
+			return true
+		}
+	}
+	return false
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// SanitizeFileName reduces a client supplied file name to its base name made
+// of a conservative character set, so it cannot traverse directories or
+// smuggle control characters into storage keys and headers.
+func SanitizeFileName(fileName string) string {
+	name := path.Base(strings.ReplaceAll(fileName, "\\", "/"))
+	name = unsafeNameChars.ReplaceAllString(name, "_")
+	name = strings.TrimLeft(name, ".")
+	if len(name) > 100 {
+		ext := filepath.Ext(name)
+		if len(ext) > 10 {
+			ext = ""
+		}
+		name = name[:100-len(ext)] + ext
+	}
+	if name == "" || name == "_" {
+		name = "file"
+	}
+	return name
+}
+
+func (g *guard) inspectOOXML(data []byte) error {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("%w: invalid package: %v", ErrUnsafe, err)
+	}
+
+	if len(r.File) > g.maxEntries {
+		return fmt.Errorf("%w: package has %d entries", ErrUnsafe, len(r.File))
+	}
+
+	var total uint64
+	for _, f := range r.File {
+		name := strings.ToLower(f.Name)
+		if strings.HasPrefix(name, "/") || strings.Contains(name, "..") || strings.Contains(name, "\\") {
+			return fmt.Errorf("%w: invalid entry name %q", ErrUnsafe, f.Name)
+		}
+
+		// The sizes in the headers are written by the uploader, so they only
+		// reject honest bombs cheaply; every entry is then decompressed and
+		// the bytes it really expands to are counted.
+		if f.UncompressedSize64 > g.maxUncompressedSize-total {
+			return fmt.Errorf("%w: package expands beyond the size limit", ErrUnsafe)
+		}
+		if f.CompressedSize64 > 0 && f.UncompressedSize64/f.CompressedSize64 > g.maxCompressionRatio {
+			return fmt.Errorf("%w: entry %q has a suspicious compression ratio", ErrUnsafe, f.Name)
+		}
+		size, err := g.expandedSize(f, g.maxUncompressedSize-total)
+		if err != nil {
+			return err
+		}
+		total += size
+
+		switch {
+		case strings.HasSuffix(name, "vbaproject.bin") || strings.HasSuffix(name, "vbadata.xml"):
+			return fmt.Errorf("%w: macros are not allowed", ErrUnsafe)
+		case strings.Contains(name, "/embeddings/") || strings.Contains(name, "/activex/"):
+			return fmt.Errorf("%w: embedded objects are not allowed", ErrUnsafe)
+		case strings.HasSuffix(name, ".rels"):
+			if err := g.inspectRelationships(f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expandedSize decompresses an entry and returns how many bytes it expands
+// to. It stops with ErrUnsafe as soon as the entry passes budget or the
+// compression ratio limit.
+func (g *guard) expandedSize(f *zip.File, budget uint64) (uint64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return 0, fmt.Errorf("%w: unreadable entry %q", ErrUnsafe, f.Name)
+	}
+	defer rc.Close()
+
+	limit, ratioBound := budget, false
+	if f.CompressedSize64 <= budget/g.maxCompressionRatio {
+		limit, ratioBound = f.CompressedSize64*g.maxCompressionRatio, true
+	}
+
+	n, err := io.Copy(io.Discard, utils.LimitReader(rc, int64(limit)))
+	switch {
+	case errors.Is(err, utils.ErrLimitExceeded) && ratioBound:
+		return 0, fmt.Errorf("%w: entry %q has a suspicious compression ratio", ErrUnsafe, f.Name)
+	case errors.Is(err, utils.ErrLimitExceeded):
+		return 0, fmt.Errorf("%w: package expands beyond the size limit", ErrUnsafe)
+	case err != nil:
+		// archive/zip fails an entry that expands past its declared size or
+		// does not match its checksum.
+		return 0, fmt.Errorf("%w: corrupt entry %q: %v", ErrUnsafe, f.Name, err)
+	}
+	return uint64(n), nil
+}
+
+type relationships struct {
+	Relationships []struct {
+		Type       string `xml:"Type,attr"`
+		Target     string `xml:"Target,attr"`
+		TargetMode string `xml:"TargetMode,attr"`
+	} `xml:"Relationship"`
+}
+
+// inspectRelationships rejects relationships that make Office fetch or run
+// something outside the package, such as remote templates, linked OLE
+// objects or external images. Plain hyperlinks are allowed.
+func (g *guard) inspectRelationships(f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: unreadable entry %q", ErrUnsafe, f.Name)
+	}
+	defer rc.Close()
+
+	var rels relationships
+	// The reader is bounded because the declared size of an entry cannot be
+	// trusted.
+	if err := xml.NewDecoder(io.LimitReader(rc, 1<<20)).Decode(&rels); err != nil {
+		return fmt.Errorf("%w: invalid relationships in %q", ErrUnsafe, f.Name)
+	}
+
+	for _, rel := range rels.Relationships {
+		if strings.HasSuffix(rel.Type, "/oleObject") || strings.HasSuffix(rel.Type, "/package") {
+			return fmt.Errorf("%w: embedded objects are not allowed", ErrUnsafe)
+		}
+		if strings.EqualFold(rel.TargetMode, "External") && !strings.HasSuffix(rel.Type, "/hyperlink") {
+			return fmt.Errorf("%w: external relationship to %q", ErrUnsafe, rel.Target)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package upload
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"hash/crc32"
+	"testing"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+)
+
+// packageEntry is one entry of a test archive. Declared, when set, replaces
+// the real uncompressed size in the headers.
+type packageEntry struct {
+	name     string
+	data     []byte
+	declared uint64
+}
+
+// buildPackage writes a zip archive whose size headers say whatever the
+// entries declare, the way a hand-crafted bomb would.
+func buildPackage(t *testing.T, entries ...packageEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		var compressed bytes.Buffer
+		fw, err := flate.NewWriter(&compressed, flate.BestCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(entry.data)
+		fw.Close()
+
+		size := uint64(len(entry.data))
+		if entry.declared > 0 {
+			size = entry.declared
+		}
+		raw, err := w.CreateRaw(&zip.FileHeader{
+			Name:               entry.name,
+			Method:             zip.Deflate,
+			CRC32:              crc32.ChecksumIEEE(entry.data),
+			CompressedSize64:   uint64(compressed.Len()),
+			UncompressedSize64: size,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw.Write(compressed.Bytes())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newTestGuard(maxUncompressedSize int64, maxCompressionRatio int) *guard {
+	return NewGuard(&config.Config{Upload: &config.Upload{
+		MaxUncompressedSize: maxUncompressedSize,
+		MaxCompressionRatio: maxCompressionRatio,
+	}}).(*guard)
+}
+
+func TestInspectOOXMLAcceptsPlainPackage(t *testing.T) {
+	g := newTestGuard(1<<20, 100)
+	data := buildPackage(t,
+		packageEntry{name: "[Content_Types].xml", data: []byte(`<Types/>`)},
+		packageEntry{name: "word/document.xml", data: []byte(`<w:document><w:body><w:p>Hello</w:p></w:body></w:document>`)},
+	)
+	if err := g.inspectOOXML(data); err != nil {
+		t.Fatalf("plain package rejected: %v", err)
+	}
+}
+
+func TestInspectOOXMLRejectsForgedSizes(t *testing.T) {
+	bomb := bytes.Repeat([]byte{0}, 8<<20)
+
+	tests := []struct {
+		name    string
+		guard   *guard
+		entries []packageEntry
+	}{
+		{
+			// Small declared sizes pass the header checks; the entry
+			// expands far past them.
+			name:    "understated size",
+			guard:   newTestGuard(1<<20, 1<<20),
+			entries: []packageEntry{{name: "word/document.xml", data: bomb, declared: 1024}},
+		},
+		{
+			// Each header is honest about a small size but the package as a
+			// whole expands past the limit.
+			name:  "total over the limit",
+			guard: newTestGuard(3<<20, 1<<20),
+			entries: []packageEntry{
+				{name: "word/media/a.bin", data: bomb[:2<<20]},
+				{name: "word/media/b.bin", data: bomb[:2<<20]},
+			},
+		},
+		{
+			name:    "ratio over the limit",
+			guard:   newTestGuard(64<<20, 100),
+			entries: []packageEntry{{name: "word/document.xml", data: bomb, declared: 4096}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.guard.inspectOOXML(buildPackage(t, tt.entries...))
+			if !errors.Is(err, ErrUnsafe) {
+				t.Fatalf("err = %v, want ErrUnsafe", err)
+			}
+		})
+	}
+}
+
+func TestExpandedSizeCountsDecompressedBytes(t *testing.T) {
+	g := newTestGuard(1<<20, 1<<20)
+	text := bytes.Repeat([]byte("a"), 512<<10)
+	data := buildPackage(t,
+		packageEntry{name: "word/document.xml", data: text},
+		packageEntry{name: "word/styles.xml", data: text, declared: 100},
+	)
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := g.expandedSize(r.File[0], 1<<20)
+	if err != nil || size != uint64(len(text)) {
+		t.Fatalf("expandedSize = %d, %v, want %d", size, err, len(text))
+	}
+	// The budget is enforced on the bytes read, not on the header.
+	if _, err := g.expandedSize(r.File[0], 64<<10); !errors.Is(err, ErrUnsafe) {
+		t.Fatalf("over budget: err = %v, want ErrUnsafe", err)
+	}
+	// An entry that expands past what it declared is rejected even when
+	// the budget would hold it.
+	if _, err := g.expandedSize(r.File[1], 1<<20); !errors.Is(err, ErrUnsafe) {
+		t.Fatalf("understated entry: err = %v, want ErrUnsafe", err)
+	}
+}
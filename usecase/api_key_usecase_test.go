@@ -0,0 +1,130 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/apperror"
+	"github.com/IlhamSetiaji/report-converter/auth"
+	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/IlhamSetiaji/report-converter/dto"
+	"github.com/IlhamSetiaji/report-converter/entity"
+	"github.com/IlhamSetiaji/report-converter/rbac"
+	"github.com/IlhamSetiaji/report-converter/repository"
+	"github.com/IlhamSetiaji/report-converter/request"
+	"github.com/google/uuid"
+)
+
+// memoryRoleRepository serves role assignments from a subject to roles map.
+type memoryRoleRepository struct {
+	repository.IRoleRepository
+	roles map[string][]rbac.Role
+}
+
+func (r *memoryRoleRepository) FindRoleAssignments(ctx context.Context, subject string) ([]*entity.RoleAssignment, error) {
+	var assignments []*entity.RoleAssignment
+	for _, role := range r.roles[subject] {
+		assignments = append(assignments, &entity.RoleAssignment{Subject: subject, Role: string(role)})
+	}
+	return assignments, nil
+}
+
+// memoryApiKeyRepository stores created keys by hash.
+type memoryApiKeyRepository struct {
+	repository.IApiKeyRepository
+	keys map[string]*entity.ApiKey
+}
+
+func (r *memoryApiKeyRepository) CreateApiKey(ctx context.Context, apiKey *entity.ApiKey) (*entity.ApiKey, error) {
+	apiKey.ID = uuid.New()
+	r.keys[apiKey.KeyHash] = apiKey
+	return apiKey, nil
+}
+
+func (r *memoryApiKeyRepository) FindApiKeyByHash(ctx context.Context, hash string) (*entity.ApiKey, error) {
+	return r.keys[hash], nil
+}
+
+func (r *memoryApiKeyRepository) TouchApiKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	return nil
+}
+
+func newTestApiKeyUseCase(roles map[string][]rbac.Role) (IApiKeyUseCase, IRoleUseCase) {
+	roleUseCase := NewRoleUseCase(&memoryRoleRepository{roles: roles}, &config.Config{})
+	apiKeys := &memoryApiKeyRepository{keys: map[string]*entity.ApiKey{}}
+	return NewApiKeyUseCase(apiKeys, dto.NewApiKeyDTO(), roleUseCase), roleUseCase
+}
+
+func TestApiKeyCannotEscalateBeyondCreator(t *testing.T) {
+	ctx := context.Background()
+	apiKeys, roles := newTestApiKeyUseCase(map[string][]rbac.Role{
+		"alice": {rbac.RolePublisher},
+	})
+	alice := &auth.Principal{Subject: "alice", Method: "jwt"}
+
+	// Holding apikey:manage is checked by the route; here alice, a publisher,
+	// must not mint a key carrying role:manage.
+	_, err := apiKeys.CreateApiKey(ctx, &request.CreateApiKeyRequest{
+		Name:   "escalate",
+		Scopes: []string{string(rbac.PermissionRoleManage)},
+	}, alice)
+	if apperror.CodeOf(err) != apperror.CodeForbidden {
+		t.Fatalf("creating a key with role:manage: err = %v, want forbidden", err)
+	}
+
+	created, err := apiKeys.CreateApiKey(ctx, &request.CreateApiKeyRequest{
+		Name:   "render",
+		Scopes: []string{string(rbac.PermissionTemplateRender)},
+	}, alice)
+	if err != nil {
+		t.Fatalf("creating a key within alice's permissions: %v", err)
+	}
+
+	principal, err := apiKeys.VerifyApiKey(ctx, created.Key)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	permissions, err := roles.ResolvePermissions(ctx, principal)
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if !permissions.Has(rbac.PermissionTemplateRender) {
+		t.Error("key lost the render permission it was scoped to")
+	}
+	for _, p := range []rbac.Permission{rbac.PermissionTemplateUpload, rbac.PermissionTemplatePublish, rbac.PermissionRoleManage} {
+		if permissions.Has(p) {
+			t.Errorf("key scoped to template:render also holds %s", p)
+		}
+	}
+
+	// A key cannot mint a key wider than its own scopes either.
+	_, err = apiKeys.CreateApiKey(ctx, &request.CreateApiKeyRequest{
+		Name:   "wider",
+		Scopes: []string{string(rbac.PermissionTemplatePublish)},
+	}, principal)
+	if apperror.CodeOf(err) != apperror.CodeForbidden {
+		t.Fatalf("key minting a wider key: err = %v, want forbidden", err)
+	}
+}
+
+func TestScopesDoNotWidenRolePermissions(t *testing.T) {
+	ctx := context.Background()
+	_, roles := newTestApiKeyUseCase(map[string][]rbac.Role{
+		"bob": {rbac.RoleViewer},
+	})
+
+	permissions, err := roles.ResolvePermissions(ctx, &auth.Principal{
+		Subject: "bob",
+		Scopes:  []string{string(rbac.PermissionTemplateView), string(rbac.PermissionRoleManage)},
+	})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if permissions.Has(rbac.PermissionRoleManage) {
+		t.Error("a role:manage scope granted a viewer role:manage")
+	}
+	if !permissions.Has(rbac.PermissionTemplateView) {
+		t.Error("viewer lost template:view")
+	}
+}
@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/entity"
+	"github.com/IlhamSetiaji/report-converter/repository"
+	"github.com/IlhamSetiaji/report-converter/request"
+	"github.com/IlhamSetiaji/report-converter/response"
+	"github.com/google/uuid"
+)
+
+var auditCSVHeader = []string{
+	"id", "created_at", "actor", "action", "template_id", "template_version",
+	"ip", "user_agent", "data_hash", "details",
+}
+
+type IAuditUseCase interface {
+	FindAuditLogs(ctx context.Context, req *request.AuditLogListRequest) ([]*response.AuditLogResponse, int64, error)
+	ExportAuditLogs(ctx context.Context, req *request.AuditLogListRequest, w io.Writer) error
+}
+
+type AuditUseCase struct {
+	auditRepository repository.IAuditRepository
+}
+
+func NewAuditUseCase(auditRepository repository.IAuditRepository) IAuditUseCase {
+	return &AuditUseCase{
+		auditRepository: auditRepository,
+	}
+}
+
+func (a *AuditUseCase) FindAuditLogs(ctx context.Context, req *request.AuditLogListRequest) ([]*response.AuditLogResponse, int64, error) {
+	filter, err := auditLogFilter(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	filter.Offset = (req.Page - 1) * req.Limit
+	filter.Limit = req.Limit
+
+	logs, total, err := a.auditRepository.FindAuditLogs(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]*response.AuditLogResponse, 0, len(logs))
+	for i := range logs {
+		responses = append(responses, convertAuditLog(&logs[i]))
+	}
+	return responses, total, nil
+}
+
+// ExportAuditLogs writes every entry matching req to w as CSV, oldest first.
+func (a *AuditUseCase) ExportAuditLogs(ctx context.Context, req *request.AuditLogListRequest, w io.Writer) error {
+	filter, err := auditLogFilter(req)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(auditCSVHeader); err != nil {
+		return err
+	}
+
+	err = a.auditRepository.ExportAuditLogs(ctx, filter, func(logs []entity.AuditLog) error {
+		for i := range logs {
+			log := convertAuditLog(&logs[i])
+			details := ""
+			if log.Details != nil {
+				raw, err := json.Marshal(log.Details)
+				if err != nil {
+					return err
+				}
+				details = string(raw)
+			}
+
+			record := []string{
+				log.ID,
+				log.CreatedAt,
+				log.Actor,
+				log.Action,
+				log.TemplateID,
+				strconv.Itoa(log.TemplateVersion),
+				log.IP,
+				log.UserAgent,
+				log.DataHash,
+				details,
+			}
+			for i := range record {
+				record[i] = csvCell(record[i])
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvCell keeps a spreadsheet from reading a cell as a formula. Actors and
+// user agents come from callers, so a cell starting with a formula character
+// is prefixed with a quote.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func auditLogFilter(req *request.AuditLogListRequest) (*repository.AuditLogFilter, error) {
+	filter := &repository.AuditLogFilter{
+		Actor:  req.Actor,
+		Action: req.Action,
+	}
+
+	if req.TemplateID != "" {
+		id, err := uuid.Parse(req.TemplateID)
+		if err != nil {
+			return nil, err
+		}
+		filter.TemplateID = &id
+	}
+	if req.From != "" {
+		from, err := time.ParseInLocation(time.DateOnly, req.From, time.Local)
+		if err != nil {
+			return nil, err
+		}
+		filter.From = &from
+	}
+	if req.To != "" {
+		to, err := time.ParseInLocation(time.DateOnly, req.To, time.Local)
+		if err != nil {
+			return nil, err
+		}
+		// to is inclusive of the whole day.
+		to = to.AddDate(0, 0, 1)
+		filter.To = &to
+	}
+	return filter, nil
+}
+
+func convertAuditLog(ent *entity.AuditLog) *response.AuditLogResponse {
+	var templateID string
+	if ent.TemplateID != nil {
+		templateID = ent.TemplateID.String()
+	}
+
+	return &response.AuditLogResponse{
+		ID:              ent.ID.String(),
+		Actor:           ent.Actor,
+		Action:          string(ent.Action),
+		TemplateID:      templateID,
+		TemplateVersion: ent.TemplateVersion,
+		IP:              ent.IP,
+		UserAgent:       ent.UserAgent,
+		DataHash:        ent.DataHash,
+		Details:         ent.Details,
+		CreatedAt:       ent.CreatedAt.Format(time.RFC3339),
+	}
+}
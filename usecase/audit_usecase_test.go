@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/entity"
+	"github.com/IlhamSetiaji/report-converter/repository"
+	"github.com/IlhamSetiaji/report-converter/request"
+	"github.com/IlhamSetiaji/report-converter/response"
+	"github.com/google/uuid"
+)
+
+// memoryAuditRepository keeps audit entries in insertion order.
+type memoryAuditRepository struct {
+	repository.IAuditRepository
+	logs []entity.AuditLog
+}
+
+func (r *memoryAuditRepository) CreateAuditLog(ctx context.Context, log *entity.AuditLog) error {
+	r.logs = append(r.logs, *log)
+	return nil
+}
+
+func (r *memoryAuditRepository) ExportAuditLogs(ctx context.Context, filter *repository.AuditLogFilter, fn func([]entity.AuditLog) error) error {
+	return fn(r.logs)
+}
+
+func TestAuditExportNeutralisesFormulas(t *testing.T) {
+	repo := &memoryAuditRepository{logs: []entity.AuditLog{{
+		ID:        uuid.New(),
+		Actor:     "=HYPERLINK(\"https://evil.example\")",
+		Action:    entity.AuditActionTemplateRender,
+		UserAgent: "@SUM(1+1)",
+		IP:        "-1+1",
+		CreatedAt: time.Now(),
+	}}}
+
+	var out bytes.Buffer
+	if err := NewAuditUseCase(repo).ExportAuditLogs(context.Background(), &request.AuditLogListRequest{}, &out); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	row := records[1]
+	for column, want := range map[int]string{
+		2: `'=HYPERLINK("https://evil.example")`,
+		3: "template.render",
+		6: "'-1+1",
+		7: "'@SUM(1+1)",
+	} {
+		if row[column] != want {
+			t.Errorf("%s = %q, want %q", records[0][column], row[column], want)
+		}
+	}
+}
+
+func TestRenderDataHashIsKeyed(t *testing.T) {
+	uc, _, _ := newTestTemplateUseCase(t)
+	audits := &memoryAuditRepository{}
+	uc.(*TemplateUseCase).auditRepository = audits
+
+	template := &response.TemplateResponse{ID: uuid.NewString(), Version: 1}
+	data := map[string]interface{}{"nik": "3171234567890001"}
+	if err := uc.RecordRender(context.Background(), template, data, "pdf", false); err != nil {
+		t.Fatalf("record render: %v", err)
+	}
+
+	plain := sha256.Sum256([]byte(`{"nik":"3171234567890001"}`))
+	hash := audits.logs[0].DataHash
+	if len(hash) != 64 || hash == hex.EncodeToString(plain[:]) {
+		t.Errorf("data hash %q is not a keyed hash of the payload", hash)
+	}
+}
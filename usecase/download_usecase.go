@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/apperror"
+	"github.com/IlhamSetiaji/report-converter/repository"
+	"github.com/IlhamSetiaji/report-converter/request"
+	"github.com/IlhamSetiaji/report-converter/signer"
+	"github.com/IlhamSetiaji/report-converter/storage"
+)
+
+// ErrDownloadConsumed is returned when a single-use link is used twice.
+var ErrDownloadConsumed = errors.New("download link has already been used")
+
+type IDownloadUseCase interface {
+	OpenDownload(ctx context.Context, req *request.DownloadRequest) (io.ReadCloser, error)
+}
+
+type DownloadUseCase struct {
+	downloadRepository repository.IDownloadRepository
+	signer             signer.URLSigner
+	storage            storage.Storage
+}
+
+func NewDownloadUseCase(downloadRepository repository.IDownloadRepository, signer signer.URLSigner, storage storage.Storage) IDownloadUseCase {
+	return &DownloadUseCase{
+		downloadRepository: downloadRepository,
+		signer:             signer,
+		storage:            storage,
+	}
+}
+
+// OpenDownload verifies a signed link and opens the object it points to.
+// The nonce of a single-use link is consumed before the object is opened,
+// so a link that fails half way cannot be replayed either.
+func (d *DownloadUseCase) OpenDownload(ctx context.Context, req *request.DownloadRequest) (io.ReadCloser, error) {
+	err := d.signer.Verify(&signer.SignedURL{
+		Key:       req.Key,
+		Expires:   req.Expires,
+		Nonce:     req.Nonce,
+		Signature: req.Signature,
+	})
+	switch {
+	case errors.Is(err, signer.ErrInvalidSignature):
+		return nil, apperror.WrapAs(err, apperror.CodeForbidden, "Invalid download link")
+	case errors.Is(err, signer.ErrExpired):
+		return nil, apperror.WrapAs(err, apperror.CodeGone, "Download link is no longer valid")
+	case err != nil:
+		return nil, err
+	}
+
+	if req.Nonce != "" {
+		consumed, err := d.downloadRepository.ConsumeNonce(ctx, req.Nonce, time.Unix(req.Expires, 0))
+		if err != nil {
+			return nil, err
+		}
+		if !consumed {
+			return nil, apperror.WrapAs(ErrDownloadConsumed, apperror.CodeGone, "Download link is no longer valid")
+		}
+	}
+
+	file, err := d.storage.Get(ctx, req.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, apperror.NotFound("File not found")
+	}
+	return file, err
+}
@@ -0,0 +1,105 @@
+package usecase
+
+import (
+	"context"
+	"time"
+	// Quota and usage periods follow the Asia/Jakarta calendar, so the zone
+	// has to load on hosts and images without a time zone database.
+	_ "time/tzdata"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/IlhamSetiaji/report-converter/entity"
+	"github.com/IlhamSetiaji/report-converter/repository"
+)
+
+// QuotaResult reports whether a render fits the tenant's quotas. When it
+// does not, Period is the exhausted period and Reset is when it starts over.
+type QuotaResult struct {
+	Allowed   bool
+	Period    entity.QuotaPeriod
+	Limit     int
+	Remaining int
+	Reset     time.Time
+
+	// charged are the limits the render was counted against, kept so a
+	// refund reaches the same periods even after midnight.
+	charged []repository.QuotaLimit
+}
+
+type IQuotaUseCase interface {
+	ConsumeRender(ctx context.Context) (*QuotaResult, error)
+	RefundRender(ctx context.Context, result *QuotaResult) error
+}
+
+type QuotaUseCase struct {
+	quotaRepository repository.IQuotaRepository
+	daily           int
+	monthly         int
+}
+
+func NewQuotaUseCase(quotaRepository repository.IQuotaRepository, conf *config.Config) IQuotaUseCase {
+	q := &QuotaUseCase{quotaRepository: quotaRepository}
+	if conf.Quota != nil {
+		q.daily = conf.Quota.Daily
+		q.monthly = conf.Quota.Monthly
+	}
+	return q
+}
+
+// ConsumeRender counts a render against the daily and monthly quota of the
+// tenant of ctx. Periods follow the service's Asia/Jakarta calendar.
+func (q *QuotaUseCase) ConsumeRender(ctx context.Context) (*QuotaResult, error) {
+	loc, _ := time.LoadLocation("Asia/Jakarta")
+	now := time.Now().In(loc)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	resets := map[entity.QuotaPeriod]time.Time{
+		entity.QuotaPeriodDay:   day.AddDate(0, 0, 1),
+		entity.QuotaPeriodMonth: month.AddDate(0, 1, 0),
+	}
+
+	var limits []repository.QuotaLimit
+	if q.daily > 0 {
+		limits = append(limits, repository.QuotaLimit{Period: entity.QuotaPeriodDay, Start: day, Max: q.daily})
+	}
+	if q.monthly > 0 {
+		limits = append(limits, repository.QuotaLimit{Period: entity.QuotaPeriodMonth, Start: month, Max: q.monthly})
+	}
+	if len(limits) == 0 {
+		return &QuotaResult{Allowed: true}, nil
+	}
+
+	exceeded, counts, err := q.quotaRepository.ConsumeRenderQuota(ctx, limits)
+	if err != nil {
+		return nil, err
+	}
+	if exceeded != nil {
+		return &QuotaResult{
+			Period: exceeded.Period,
+			Limit:  exceeded.Max,
+			Reset:  resets[exceeded.Period],
+		}, nil
+	}
+
+	// Report the period closest to running out.
+	result := &QuotaResult{Allowed: true, Remaining: -1, charged: limits}
+	for _, limit := range limits {
+		remaining := limit.Max - counts[limit.Period]
+		if result.Remaining < 0 || remaining < result.Remaining {
+			result.Period = limit.Period
+			result.Limit = limit.Max
+			result.Remaining = remaining
+			result.Reset = resets[limit.Period]
+		}
+	}
+	return result, nil
+}
+
+// RefundRender takes back a render ConsumeRender counted, for renders that
+// did not produce a document.
+func (q *QuotaUseCase) RefundRender(ctx context.Context, result *QuotaResult) error {
+	if result == nil || len(result.charged) == 0 {
+		return nil
+	}
+	return q.quotaRepository.RefundRenderQuota(ctx, result.charged)
+}
@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/apperror"
+	"github.com/IlhamSetiaji/report-converter/auth"
+	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/IlhamSetiaji/report-converter/entity"
+	"github.com/IlhamSetiaji/report-converter/rbac"
+	"github.com/IlhamSetiaji/report-converter/repository"
+	"github.com/IlhamSetiaji/report-converter/request"
+	"github.com/IlhamSetiaji/report-converter/response"
+	"github.com/google/uuid"
+)
+
+type IRoleUseCase interface {
+	FindRoles() []*response.RoleResponse
+	AssignRole(ctx context.Context, req *request.AssignRoleRequest, grantedBy string) (*response.RoleAssignmentResponse, error)
+	FindRoleAssignments(ctx context.Context, req *request.RoleAssignmentListRequest) ([]*response.RoleAssignmentResponse, error)
+	RevokeRole(ctx context.Context, id string) (bool, error)
+	ResolvePermissions(ctx context.Context, principal *auth.Principal) (rbac.PermissionSet, error)
+}
+
+type RoleUseCase struct {
+	roleRepository repository.IRoleRepository
+	admins         map[string]bool
+}
+
+func NewRoleUseCase(roleRepository repository.IRoleRepository, conf *config.Config) IRoleUseCase {
+	admins := make(map[string]bool)
+	if conf.Auth != nil {
+		for _, subject := range conf.Auth.Admins {
+			admins[subject] = true
+		}
+	}
+
+	return &RoleUseCase{
+		roleRepository: roleRepository,
+		admins:         admins,
+	}
+}
+
+func (r *RoleUseCase) FindRoles() []*response.RoleResponse {
+	var roles []*response.RoleResponse
+	for role, permissions := range rbac.Roles() {
+		names := make([]string, 0, len(permissions))
+		for _, p := range permissions {
+			names = append(names, string(p))
+		}
+		roles = append(roles, &response.RoleResponse{Role: string(role), Permissions: names})
+	}
+
+	sort.Slice(roles, func(i, j int) bool {
+		return len(roles[i].Permissions) < len(roles[j].Permissions)
+	})
+	return roles
+}
+
+func (r *RoleUseCase) AssignRole(ctx context.Context, req *request.AssignRoleRequest, grantedBy string) (*response.RoleAssignmentResponse, error) {
+	assignment, err := r.roleRepository.CreateRoleAssignment(ctx, &entity.RoleAssignment{
+		Subject:   req.Subject,
+		Role:      req.Role,
+		GrantedBy: grantedBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return convertRoleAssignment(assignment), nil
+}
+
+func (r *RoleUseCase) FindRoleAssignments(ctx context.Context, req *request.RoleAssignmentListRequest) ([]*response.RoleAssignmentResponse, error) {
+	assignments, err := r.roleRepository.FindRoleAssignments(ctx, req.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*response.RoleAssignmentResponse, 0, len(assignments))
+	for _, assignment := range assignments {
+		responses = append(responses, convertRoleAssignment(assignment))
+	}
+	return responses, nil
+}
+
+// RevokeRole removes an assignment and reports whether it existed.
+func (r *RoleUseCase) RevokeRole(ctx context.Context, id string) (bool, error) {
+	parsedId, err := uuid.Parse(id)
+	if err != nil {
+		return false, apperror.Validation("Invalid role assignment ID", err)
+	}
+	return r.roleRepository.DeleteRoleAssignment(ctx, parsedId)
+}
+
+// ResolvePermissions collects what a caller may do in the tenant of ctx: the
+// permissions of the roles assigned to its subject, or to the owner of an API
+// key. Scopes naming permissions narrow that set and never widen it; a caller
+// without any keeps all of its role permissions. Anonymous callers only exist
+// with authentication disabled and may do everything.
+func (r *RoleUseCase) ResolvePermissions(ctx context.Context, principal *auth.Principal) (rbac.PermissionSet, error) {
+	permissions := rbac.PermissionSet{}
+	if principal == nil {
+		permissions.Grant(rbac.RoleAdmin)
+		return permissions, nil
+	}
+
+	subject := principal.Subject
+	if principal.Owner != "" {
+		subject = principal.Owner
+	}
+	if r.admins[subject] {
+		permissions.Grant(rbac.RoleAdmin)
+	} else {
+		assignments, err := r.roleRepository.FindRoleAssignments(ctx, subject)
+		if err != nil {
+			return nil, err
+		}
+		for _, assignment := range assignments {
+			permissions.Grant(rbac.Role(assignment.Role))
+		}
+	}
+
+	scoped := rbac.PermissionSet{}
+	for _, scope := range principal.Scopes {
+		if rbac.IsPermission(scope) {
+			scoped[rbac.Permission(scope)] = true
+		}
+	}
+	if len(scoped) == 0 {
+		return permissions, nil
+	}
+	for permission := range permissions {
+		if !scoped.Has(permission) {
+			delete(permissions, permission)
+		}
+	}
+	return permissions, nil
+}
+
+func convertRoleAssignment(ent *entity.RoleAssignment) *response.RoleAssignmentResponse {
+	return &response.RoleAssignmentResponse{
+		ID:        ent.ID.String(),
+		Subject:   ent.Subject,
+		Role:      ent.Role,
+		GrantedBy: ent.GrantedBy,
+		CreatedAt: ent.CreatedAt.Format(time.RFC3339),
+	}
+}
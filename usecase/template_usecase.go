@@ -1,88 +1,881 @@
 package usecase
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/apperror"
+	"github.com/IlhamSetiaji/report-converter/audit"
+	"github.com/IlhamSetiaji/report-converter/config"
 	"github.com/IlhamSetiaji/report-converter/dto"
 	"github.com/IlhamSetiaji/report-converter/entity"
+	"github.com/IlhamSetiaji/report-converter/extractor"
+	"github.com/IlhamSetiaji/report-converter/logger"
 	"github.com/IlhamSetiaji/report-converter/repository"
 	"github.com/IlhamSetiaji/report-converter/request"
 	"github.com/IlhamSetiaji/report-converter/response"
+	"github.com/IlhamSetiaji/report-converter/signer"
+	"github.com/IlhamSetiaji/report-converter/storage"
+	"github.com/IlhamSetiaji/report-converter/tenant"
+	"github.com/IlhamSetiaji/report-converter/tracing"
+	"github.com/IlhamSetiaji/report-converter/utils"
 	"github.com/google/uuid"
 )
 
 type ITemplateUseCase interface {
-	CreateTemplate(template *request.TemplateRequest) (*response.TemplateResponse, error)
-	FindAllTemplate() ([]*response.TemplateResponse, error)
-	FindTemplateByID(id string) (*response.TemplateResponse, error)
-	DeleteTemplateByID(id string) error
+	CreateTemplate(ctx context.Context, template *request.TemplateRequest) (*response.TemplateResponse, error)
+	FindAllTemplate(ctx context.Context, req *request.TemplateListRequest) ([]*response.TemplateResponse, int64, error)
+	FindTemplateByID(ctx context.Context, id string) (*response.TemplateResponse, error)
+	DeleteTemplateByID(ctx context.Context, id string) error
+	ReplaceTemplateFile(ctx context.Context, id string, req *request.ReplaceTemplateFileRequest) (*response.TemplateResponse, error)
+	FindTemplateVersions(ctx context.Context, id string) ([]*response.TemplateVersionResponse, error)
+	RollbackTemplate(ctx context.Context, id string, req *request.RollbackTemplateRequest) (*response.TemplateResponse, error)
+	ResolveTemplate(ctx context.Context, ref string, published bool) (*response.TemplateResponse, error)
+	SubmitTemplate(ctx context.Context, id string) (*response.TemplateResponse, error)
+	ApproveTemplate(ctx context.Context, id string, req *request.ReviewTemplateRequest) (*response.TemplateResponse, error)
+	RejectTemplate(ctx context.Context, id string, req *request.ReviewTemplateRequest) (*response.TemplateResponse, error)
+	ArchiveTemplate(ctx context.Context, id string) (*response.TemplateResponse, error)
+	SearchTemplates(ctx context.Context, req *request.TemplateSearchRequest) ([]*response.TemplateSearchResponse, int64, error)
+	UpdateTemplateMetadata(ctx context.Context, id string, req *request.UpdateTemplateMetadataRequest) (*response.TemplateResponse, error)
+	RestoreTemplateByID(ctx context.Context, id string) (*response.TemplateResponse, error)
+	PurgeTemplateByID(ctx context.Context, id string) (*response.TemplateResponse, error)
+	PurgeExpiredTemplates(ctx context.Context, retention time.Duration) (int, error)
+	CreateDownloadURL(ctx context.Context, id string, req *request.DownloadURLRequest) (*response.DownloadURLResponse, error)
+	RecordRender(ctx context.Context, template *response.TemplateResponse, data map[string]interface{}, outputFormat string, preview bool) error
 }
 
+// templateTrashDir holds the files of soft-deleted templates until they are
+// purged. It mirrors the layout below storage/.
+const templateTrashDir = "storage/trash"
+
+var (
+	// ErrInvalidStatusTransition is returned when a lifecycle action is not
+	// allowed from the template's current status.
+	ErrInvalidStatusTransition = errors.New("invalid template status transition")
+
+	errTemplateNotFound        = apperror.NotFound("Template not found")
+	errTemplateVersionNotFound = apperror.NotFound("Template version not found")
+)
+
 type TemplateUseCase struct {
 	templateRepository repository.ITemplateRepository
+	auditRepository    repository.IAuditRepository
 	templateDTO        dto.ITemplateDTO
+	storage            storage.Storage
+	signer             signer.URLSigner
+	dataHashKey        []byte
 }
 
-func NewTemplateUseCase(templateRepository repository.ITemplateRepository, templateDTO dto.ITemplateDTO) ITemplateUseCase {
+// NewTemplateUseCase panics if audit.hashkey is missing, a placeholder or
+// shorter than config.MinSecretLength.
+func NewTemplateUseCase(
+	templateRepository repository.ITemplateRepository,
+	auditRepository repository.IAuditRepository,
+	templateDTO dto.ITemplateDTO,
+	storage storage.Storage,
+	signer signer.URLSigner,
+	conf *config.Config,
+) ITemplateUseCase {
+	var hashKey string
+	if conf.Audit != nil {
+		hashKey = conf.Audit.HashKey
+	}
+	if err := config.CheckSecret("audit.hashkey", hashKey); err != nil {
+		panic(err.Error())
+	}
+
 	return &TemplateUseCase{
 		templateRepository: templateRepository,
+		auditRepository:    auditRepository,
 		templateDTO:        templateDTO,
+		storage:            storage,
+		signer:             signer,
+		dataHashKey:        []byte(hashKey),
 	}
 }
 
-func (t *TemplateUseCase) CreateTemplate(template *request.TemplateRequest) (*response.TemplateResponse, error) {
+func (t *TemplateUseCase) CreateTemplate(ctx context.Context, template *request.TemplateRequest) (*response.TemplateResponse, error) {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.CreateTemplate")
+	defer span.End()
+
+	checksum, content, err := t.inspectTemplateFile(ctx, template.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var sampleData entity.JSONMap
+	if template.SampleData != "" {
+		if err := json.Unmarshal([]byte(template.SampleData), &sampleData); err != nil {
+			return nil, apperror.Validation("Invalid sample_data", err)
+		}
+	}
+
+	spec, ok := entity.LookupTemplateType(template.TemplateType)
+	if !ok {
+		return nil, apperror.UnsupportedType("Invalid template type", fmt.Errorf("unknown template type %s", template.TemplateType))
+	}
+	outputFormat := template.OutputFormat
+	if outputFormat == "" {
+		outputFormat = spec.DefaultOutputFormat()
+	}
+	if err := spec.CheckOutputFormat(outputFormat); err != nil {
+		return nil, apperror.Validation("Unsupported output format", err)
+	}
+
 	ent := &entity.Template{
 		Name:         template.Name,
 		TemplateType: entity.TemplateType(template.TemplateType),
 		Path:         template.Path,
+		Description:  template.Description,
+		Category:     template.Category,
+		Tags:         normalizeTags(template.Tags),
+		Owner:        template.Owner,
+		SampleData:   sampleData,
+		OutputFormat: outputFormat,
+		Content:      content,
+		Status:       entity.TemplateStatusDraft,
+		Versions: []entity.TemplateVersion{
+			{Path: template.Path, Checksum: checksum},
+		},
 	}
 
-	createdTemplate, err := t.templateRepository.CreateTemplate(ent)
+	createdTemplate, err := t.templateRepository.CreateTemplate(ctx, ent)
 	if err != nil {
 		return nil, err
 	}
 
-	return t.templateDTO.ConvertEntityToResponse(createdTemplate), nil
+	t.recordAudit(ctx, entity.AuditActionTemplateCreate, createdTemplate, createdTemplate.CurrentVersion, checksum, entity.JSONMap{
+		"name":          createdTemplate.Name,
+		"template_type": string(createdTemplate.TemplateType),
+	})
+
+	return t.templateDTO.ConvertEntityToResponse(ctx, createdTemplate), nil
 }
 
-func (t *TemplateUseCase) FindAllTemplate() ([]*response.TemplateResponse, error) {
-	templates, err := t.templateRepository.FindAllTemplate()
+func (t *TemplateUseCase) FindAllTemplate(ctx context.Context, req *request.TemplateListRequest) ([]*response.TemplateResponse, int64, error) {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.FindAllTemplate")
+	defer span.End()
+
+	filter := &repository.TemplateFilter{
+		Offset:       (req.Page - 1) * req.Limit,
+		Limit:        req.Limit,
+		TemplateType: req.TemplateType,
+		Status:       req.Status,
+		Category:     req.Category,
+		Tags:         normalizeTags(req.Tags),
+		Owner:        req.Owner,
+		OutputFormat: req.OutputFormat,
+		Search:       strings.TrimSpace(req.Search),
+		Sort:         req.Sort,
+		Desc:         req.Order == "desc",
+	}
+
+	if req.CreatedFrom != "" {
+		from, err := time.ParseInLocation(time.DateOnly, req.CreatedFrom, time.Local)
+		if err != nil {
+			return nil, 0, apperror.Validation("Invalid created_from", err)
+		}
+		filter.CreatedFrom = &from
+	}
+	if req.CreatedTo != "" {
+		to, err := time.ParseInLocation(time.DateOnly, req.CreatedTo, time.Local)
+		if err != nil {
+			return nil, 0, apperror.Validation("Invalid created_to", err)
+		}
+		// created_to is inclusive of the whole day.
+		to = to.AddDate(0, 0, 1)
+		filter.CreatedTo = &to
+	}
+
+	templates, total, err := t.templateRepository.FindAllTemplate(ctx, filter)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	var templateResponses []*response.TemplateResponse
 	for _, template := range templates {
-		templateResponses = append(templateResponses, t.templateDTO.ConvertEntityToResponse(&template))
+		templateResponses = append(templateResponses, t.templateDTO.ConvertEntityToResponse(ctx, &template))
 	}
 
-	return templateResponses, nil
+	return templateResponses, total, nil
 }
 
-func (t *TemplateUseCase) FindTemplateByID(id string) (*response.TemplateResponse, error) {
-	parsedId, err := uuid.Parse(id)
+func (t *TemplateUseCase) FindTemplateByID(ctx context.Context, id string) (*response.TemplateResponse, error) {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.FindTemplateByID")
+	defer span.End()
+
+	parsedId, err := parseTemplateID(id)
 	if err != nil {
 		return nil, err
 	}
 
-	ent, err := t.templateRepository.FindTemplateByID(parsedId)
+	ent, err := t.templateRepository.FindTemplateByID(ctx, parsedId)
 	if err != nil {
 		return nil, err
 	}
 	if ent == nil {
-		return nil, nil
+		return nil, errTemplateNotFound
 	}
 
-	return t.templateDTO.ConvertEntityToResponse(ent), nil
+	return t.templateDTO.ConvertEntityToResponse(ctx, ent), nil
 }
 
-func (t *TemplateUseCase) DeleteTemplateByID(id string) error {
-	parsedId, err := uuid.Parse(id)
+func (t *TemplateUseCase) DeleteTemplateByID(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.DeleteTemplateByID")
+	defer span.End()
+
+	parsedId, err := parseTemplateID(id)
+	if err != nil {
+		return err
+	}
+
+	ent, err := t.templateRepository.FindTemplateByID(ctx, parsedId)
+	if err != nil {
+		return err
+	}
+	if ent == nil {
+		return errTemplateNotFound
+	}
+
+	versions, err := t.templateRepository.FindTemplateVersions(ctx, parsedId)
 	if err != nil {
 		return err
 	}
+	ent.Versions = versions
 
-	err = t.templateRepository.DeleteTemplateByID(parsedId)
+	err = t.templateRepository.DeleteTemplateByID(ctx, parsedId)
 	if err != nil {
 		return err
 	}
+	t.recordAudit(ctx, entity.AuditActionTemplateDelete, ent, ent.CurrentVersion, "", nil)
+
+	// The template is deleted once its row is; a file left in place is
+	// removed from its original path by the purge.
+	for _, key := range templateFilePaths(ent) {
+		if err := t.moveIfExists(ctx, key, trashPath(key)); err != nil {
+			logger.FromContext(ctx).WithError(err).WithField("key", key).Warn("Failed to move template file to trash")
+		}
+	}
 	return nil
 }
+
+func (t *TemplateUseCase) ReplaceTemplateFile(ctx context.Context, id string, req *request.ReplaceTemplateFileRequest) (*response.TemplateResponse, error) {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.ReplaceTemplateFile")
+	defer span.End()
+
+	parsedId, err := parseTemplateID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, content, err := t.inspectTemplateFile(ctx, req.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	ent, err := t.templateRepository.CreateTemplateVersion(ctx, parsedId, req.Path, checksum, content)
+	if err != nil {
+		return nil, err
+	}
+	if ent == nil {
+		return nil, errTemplateNotFound
+	}
+
+	t.recordAudit(ctx, entity.AuditActionTemplateReplaceFile, ent, ent.CurrentVersion, checksum, nil)
+	return t.templateDTO.ConvertEntityToResponse(ctx, ent), nil
+}
+
+func (t *TemplateUseCase) FindTemplateVersions(ctx context.Context, id string) ([]*response.TemplateVersionResponse, error) {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.FindTemplateVersions")
+	defer span.End()
+
+	parsedId, err := parseTemplateID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ent, err := t.templateRepository.FindTemplateByID(ctx, parsedId)
+	if err != nil {
+		return nil, err
+	}
+	if ent == nil {
+		return nil, errTemplateNotFound
+	}
+
+	versions, err := t.templateRepository.FindTemplateVersions(ctx, parsedId)
+	if err != nil {
+		return nil, err
+	}
+
+	var versionResponses []*response.TemplateVersionResponse
+	for _, version := range versions {
+		versionResponses = append(versionResponses, t.templateDTO.ConvertVersionEntityToResponse(ctx, &version, ent.CurrentVersion))
+	}
+
+	return versionResponses, nil
+}
+
+func (t *TemplateUseCase) RollbackTemplate(ctx context.Context, id string, req *request.RollbackTemplateRequest) (*response.TemplateResponse, error) {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.RollbackTemplate")
+	defer span.End()
+
+	parsedId, err := parseTemplateID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := t.templateRepository.FindTemplateVersion(ctx, parsedId, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	if version == nil {
+		return nil, errTemplateVersionNotFound
+	}
+
+	_, content, err := t.inspectTemplateFile(ctx, version.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	ent, err := t.templateRepository.UpdateCurrentVersion(ctx, parsedId, version, content)
+	if err != nil {
+		return nil, err
+	}
+	if ent == nil {
+		return nil, errTemplateNotFound
+	}
+
+	t.recordAudit(ctx, entity.AuditActionTemplateRollback, ent, version.Version, version.Checksum, nil)
+	return t.templateDTO.ConvertEntityToResponse(ctx, ent), nil
+}
+
+// ResolveTemplate looks up the template to render from a reference of the
+// form "template_id" or "template_id@version". Without a version, published
+// resolves the published version and fails while there is none; otherwise the
+// current working copy is used. With published, a pinned version must have
+// been published at some point, so a document can be re-issued from any
+// version that was live but never from one that was not reviewed. The
+// returned response describes the resolved version, so PathOriginal points at
+// that version's file.
+func (t *TemplateUseCase) ResolveTemplate(ctx context.Context, ref string, published bool) (*response.TemplateResponse, error) {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.ResolveTemplate")
+	defer span.End()
+
+	id, versionStr, pinned := strings.Cut(ref, "@")
+	parsedId, err := parseTemplateID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ent, err := t.templateRepository.FindTemplateByID(ctx, parsedId)
+	if err != nil {
+		return nil, err
+	}
+	if ent == nil {
+		return nil, errTemplateNotFound
+	}
+
+	version := ent.CurrentVersion
+	if published {
+		if ent.Status == entity.TemplateStatusArchived || ent.PublishedVersion == 0 {
+			return nil, apperror.Conflict("Template is not published", fmt.Errorf("template status is %s", ent.Status))
+		}
+		version = ent.PublishedVersion
+	}
+	if pinned {
+		version, err = strconv.Atoi(versionStr)
+		if err != nil || version < 1 {
+			return nil, apperror.Validation("Invalid template version", fmt.Errorf("invalid template version %q", versionStr))
+		}
+	}
+
+	templateVersion, err := t.templateRepository.FindTemplateVersion(ctx, parsedId, version)
+	if err != nil {
+		return nil, err
+	}
+	if templateVersion == nil {
+		// Templates uploaded before versioning existed have no version rows;
+		// their only file is the one on the template itself.
+		if !pinned && version == ent.CurrentVersion {
+			return t.templateDTO.ConvertEntityToResponse(ctx, ent), nil
+		}
+		return nil, errTemplateVersionNotFound
+	}
+	if published && templateVersion.PublishedAt == nil {
+		return nil, apperror.Conflict("Template version is not published", fmt.Errorf("template version %d was never approved", templateVersion.Version))
+	}
+
+	ent.CurrentVersion = templateVersion.Version
+	ent.Path = templateVersion.Path
+	return t.templateDTO.ConvertEntityToResponse(ctx, ent), nil
+}
+
+func (t *TemplateUseCase) SubmitTemplate(ctx context.Context, id string) (*response.TemplateResponse, error) {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.SubmitTemplate")
+	defer span.End()
+
+	return t.transitionTemplate(ctx, id, entity.AuditActionTemplateSubmit, []entity.TemplateStatus{entity.TemplateStatusDraft}, map[string]interface{}{
+		"status": entity.TemplateStatusInReview,
+	})
+}
+
+func (t *TemplateUseCase) ApproveTemplate(ctx context.Context, id string, req *request.ReviewTemplateRequest) (*response.TemplateResponse, error) {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.ApproveTemplate")
+	defer span.End()
+
+	parsedId, err := parseTemplateID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ent, applied, err := t.templateRepository.PublishTemplate(ctx, parsedId, req.ReviewedBy, time.Now())
+	return t.finishTransition(ctx, entity.AuditActionTemplateApprove, ent, applied, err)
+}
+
+func (t *TemplateUseCase) RejectTemplate(ctx context.Context, id string, req *request.ReviewTemplateRequest) (*response.TemplateResponse, error) {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.RejectTemplate")
+	defer span.End()
+
+	return t.transitionTemplate(ctx, id, entity.AuditActionTemplateReject, []entity.TemplateStatus{entity.TemplateStatusInReview}, map[string]interface{}{
+		"status":      entity.TemplateStatusDraft,
+		"approved_by": "",
+		"approved_at": nil,
+	})
+}
+
+func (t *TemplateUseCase) ArchiveTemplate(ctx context.Context, id string) (*response.TemplateResponse, error) {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.ArchiveTemplate")
+	defer span.End()
+
+	return t.transitionTemplate(ctx, id, entity.AuditActionTemplateArchive, []entity.TemplateStatus{
+		entity.TemplateStatusDraft,
+		entity.TemplateStatusInReview,
+		entity.TemplateStatusPublished,
+	}, map[string]interface{}{
+		"status": entity.TemplateStatusArchived,
+	})
+}
+
+func (t *TemplateUseCase) transitionTemplate(ctx context.Context, id string, action entity.AuditAction, from []entity.TemplateStatus, updates map[string]interface{}) (*response.TemplateResponse, error) {
+	parsedId, err := parseTemplateID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ent, applied, err := t.templateRepository.UpdateTemplateStatus(ctx, parsedId, from, updates)
+	return t.finishTransition(ctx, action, ent, applied, err)
+}
+
+// finishTransition reports the outcome of a status update and audits it when
+// it was applied.
+func (t *TemplateUseCase) finishTransition(ctx context.Context, action entity.AuditAction, ent *entity.Template, applied bool, err error) (*response.TemplateResponse, error) {
+	if err != nil {
+		return nil, err
+	}
+	if ent == nil {
+		return nil, errTemplateNotFound
+	}
+	if !applied {
+		return nil, apperror.Conflict("Invalid status transition", fmt.Errorf("%w: template is %s", ErrInvalidStatusTransition, ent.Status))
+	}
+
+	t.recordAudit(ctx, action, ent, ent.CurrentVersion, "", entity.JSONMap{"status": string(ent.Status)})
+
+	return t.templateDTO.ConvertEntityToResponse(ctx, ent), nil
+}
+
+func (t *TemplateUseCase) SearchTemplates(ctx context.Context, req *request.TemplateSearchRequest) ([]*response.TemplateSearchResponse, int64, error) {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.SearchTemplates")
+	defer span.End()
+
+	results, total, err := t.templateRepository.SearchTemplates(ctx, req.Query, (req.Page-1)*req.Limit, req.Limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var searchResponses []*response.TemplateSearchResponse
+	for _, result := range results {
+		searchResponses = append(searchResponses, &response.TemplateSearchResponse{
+			TemplateResponse: t.templateDTO.ConvertEntityToResponse(ctx, &result.Template),
+			Snippet:          result.Snippet,
+			Rank:             result.Rank,
+		})
+	}
+
+	return searchResponses, total, nil
+}
+
+func (t *TemplateUseCase) UpdateTemplateMetadata(ctx context.Context, id string, req *request.UpdateTemplateMetadataRequest) (*response.TemplateResponse, error) {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.UpdateTemplateMetadata")
+	defer span.End()
+
+	parsedId, err := parseTemplateID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Category != nil {
+		updates["category"] = *req.Category
+	}
+	if req.Tags != nil {
+		updates["tags"] = normalizeTags(req.Tags)
+	}
+	if req.Owner != nil {
+		updates["owner"] = *req.Owner
+	}
+	if req.SampleData != nil {
+		updates["sample_data"] = entity.JSONMap(req.SampleData)
+	}
+	if req.OutputFormat != nil {
+		current, err := t.templateRepository.FindTemplateByID(ctx, parsedId)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			return nil, errTemplateNotFound
+		}
+		spec, ok := entity.LookupTemplateType(string(current.TemplateType))
+		if !ok {
+			return nil, apperror.UnsupportedType("Invalid template type", fmt.Errorf("unknown template type %s", current.TemplateType))
+		}
+		if err := spec.CheckOutputFormat(*req.OutputFormat); err != nil {
+			return nil, apperror.Validation("Unsupported output format", err)
+		}
+		updates["output_format"] = *req.OutputFormat
+	}
+
+	ent, err := t.templateRepository.UpdateTemplateMetadata(ctx, parsedId, updates)
+	if err != nil {
+		return nil, err
+	}
+	if ent == nil {
+		return nil, errTemplateNotFound
+	}
+
+	// Only the names of changed fields are audited; sample data in
+	// particular may hold personal data.
+	fields := make([]string, 0, len(updates))
+	for field := range updates {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	t.recordAudit(ctx, entity.AuditActionTemplateUpdate, ent, ent.CurrentVersion, "", entity.JSONMap{"fields": fields})
+
+	return t.templateDTO.ConvertEntityToResponse(ctx, ent), nil
+}
+
+// RestoreTemplateByID undoes a soft delete and moves the template's files back
+// out of the trash. It fails with not found when the template is not in the
+// trash.
+func (t *TemplateUseCase) RestoreTemplateByID(ctx context.Context, id string) (*response.TemplateResponse, error) {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.RestoreTemplateByID")
+	defer span.End()
+
+	parsedId, err := parseTemplateID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ent, err := t.templateRepository.FindDeletedTemplateByID(ctx, parsedId)
+	if err != nil {
+		return nil, err
+	}
+	if ent == nil {
+		return nil, errTemplateNotFound
+	}
+
+	var errs []error
+	for _, key := range templateFilePaths(ent) {
+		errs = append(errs, t.moveIfExists(ctx, trashPath(key), key))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	if err := t.templateRepository.RestoreTemplateByID(ctx, parsedId); err != nil {
+		return nil, err
+	}
+
+	ent, err = t.templateRepository.FindTemplateByID(ctx, parsedId)
+	if err != nil {
+		return nil, err
+	}
+	if ent == nil {
+		return nil, errTemplateNotFound
+	}
+	t.recordAudit(ctx, entity.AuditActionTemplateRestore, ent, ent.CurrentVersion, "", nil)
+
+	return t.templateDTO.ConvertEntityToResponse(ctx, ent), nil
+}
+
+// PurgeTemplateByID permanently removes a soft-deleted template, its versions
+// and their files. It fails with not found when the template is not in the
+// trash.
+func (t *TemplateUseCase) PurgeTemplateByID(ctx context.Context, id string) (*response.TemplateResponse, error) {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.PurgeTemplateByID")
+	defer span.End()
+
+	parsedId, err := parseTemplateID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ent, err := t.templateRepository.FindDeletedTemplateByID(ctx, parsedId)
+	if err != nil {
+		return nil, err
+	}
+	if ent == nil {
+		return nil, errTemplateNotFound
+	}
+
+	if err := t.purgeTemplate(ctx, ent); err != nil {
+		return nil, err
+	}
+
+	return t.templateDTO.ConvertEntityToResponse(ctx, ent), nil
+}
+
+// PurgeExpiredTemplates purges every template that has been in the trash for
+// longer than retention and reports how many were purged.
+func (t *TemplateUseCase) PurgeExpiredTemplates(ctx context.Context, retention time.Duration) (int, error) {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.PurgeExpiredTemplates")
+	defer span.End()
+
+	templates, err := t.templateRepository.FindDeletedTemplatesBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	var errs []error
+	for i := range templates {
+		if err := t.purgeTemplate(ctx, &templates[i]); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		purged++
+	}
+
+	return purged, errors.Join(errs...)
+}
+
+func (t *TemplateUseCase) purgeTemplate(ctx context.Context, ent *entity.Template) error {
+	if err := t.templateRepository.PurgeTemplateByID(ctx, ent.ID); err != nil {
+		return err
+	}
+	t.recordAudit(ctx, entity.AuditActionTemplatePurge, ent, ent.CurrentVersion, "", nil)
+
+	var errs []error
+	for _, key := range templateFilePaths(ent) {
+		for _, candidate := range []string{trashPath(key), key} {
+			errs = append(errs, t.storage.Delete(ctx, candidate))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// parseTemplateID parses a template ID taken from the request.
+func parseTemplateID(id string) (uuid.UUID, error) {
+	parsedId, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, apperror.Validation("Invalid template ID", err)
+	}
+	return parsedId, nil
+}
+
+// templateFilePaths lists every file owned by a template, which is the file
+// of each version plus the template's own path for unversioned rows.
+func templateFilePaths(ent *entity.Template) []string {
+	seen := map[string]bool{ent.Path: true}
+	paths := []string{ent.Path}
+	for _, version := range ent.Versions {
+		if !seen[version.Path] {
+			seen[version.Path] = true
+			paths = append(paths, version.Path)
+		}
+	}
+	return paths
+}
+
+func trashPath(key string) string {
+	return path.Join(templateTrashDir, strings.TrimPrefix(key, "storage/"))
+}
+
+// moveIfExists moves src to dst and treats a missing src as already moved,
+// so interrupted deletes and restores can simply be retried.
+func (t *TemplateUseCase) moveIfExists(ctx context.Context, src string, dst string) error {
+	err := t.storage.Move(ctx, src, dst)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// inspectTemplateFile reads a stored template file and returns its checksum
+// and searchable text.
+func (t *TemplateUseCase) inspectTemplateFile(ctx context.Context, key string) (string, string, error) {
+	data, err := storage.ReadAll(ctx, t.storage, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "", "", apperror.NotFound("Template file not found")
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	content, err := extractContent(key, data)
+	if err != nil {
+		return "", "", err
+	}
+	return utils.Checksum(data), content, nil
+}
+
+// CreateDownloadURL signs a link to the file of a template version, the
+// current version unless one is requested.
+func (t *TemplateUseCase) CreateDownloadURL(ctx context.Context, id string, req *request.DownloadURLRequest) (*response.DownloadURLResponse, error) {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.CreateDownloadURL")
+	defer span.End()
+
+	ref := id
+	if req.Version > 0 {
+		ref = id + "@" + strconv.Itoa(req.Version)
+	}
+
+	template, err := t.ResolveTemplate(ctx, ref, false)
+	if err != nil {
+		return nil, err
+	}
+
+	url, expiresAt, err := t.signer.Sign(template.PathOriginal, time.Duration(req.ExpiresIn)*time.Second, req.SingleUse)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.DownloadURLResponse{
+		Url:       url,
+		ExpiresAt: expiresAt.Format(time.RFC3339),
+		SingleUse: req.SingleUse,
+	}, nil
+}
+
+// normalizeTags lower-cases and de-duplicates tags so filtering is not
+// sensitive to how a tag was typed. Comma separated values are split.
+func normalizeTags(tags []string) entity.StringArray {
+	normalized := entity.StringArray{}
+	seen := make(map[string]bool)
+	for _, raw := range tags {
+		for _, tag := range strings.Split(raw, ",") {
+			tag = strings.ToLower(strings.TrimSpace(tag))
+			if tag == "" || seen[tag] {
+				continue
+			}
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// extractContent returns the searchable text of a template file. Formats the
+// extractor does not understand are stored without content rather than
+// rejected, since they can still be rendered.
+func extractContent(name string, data []byte) (string, error) {
+	content, err := extractor.ExtractText(name, data)
+	if errors.Is(err, extractor.ErrUnsupportedFormat) {
+		return "", nil
+	}
+	return content, err
+}
+
+// RecordRender audits a document being rendered from template. The data
+// payload is only kept as a keyed hash together with its top-level field
+// names.
+// Unlike changes, renders are audited before the document is handed out, so
+// a render that cannot be audited fails.
+func (t *TemplateUseCase) RecordRender(ctx context.Context, template *response.TemplateResponse, data map[string]interface{}, outputFormat string, preview bool) error {
+	ctx, span := tracing.Start(ctx, "TemplateUseCase.RecordRender")
+	defer span.End()
+
+	parsedId, err := uuid.Parse(template.ID)
+	if err != nil {
+		return err
+	}
+
+	// encoding/json sorts map keys, so equal payloads hash equally.
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	fields := make([]string, 0, len(data))
+	for field := range data {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	action := entity.AuditActionTemplateRender
+	if preview {
+		action = entity.AuditActionTemplatePreview
+	}
+
+	actor := audit.ActorFromContext(ctx)
+	return t.auditRepository.CreateAuditLog(ctx, &entity.AuditLog{
+		Actor:           actor.Subject,
+		Action:          action,
+		TemplateID:      &parsedId,
+		TemplateVersion: template.Version,
+		IP:              actor.IP,
+		UserAgent:       actor.UserAgent,
+		DataHash:        t.dataHash(payload),
+		Details: entity.JSONMap{
+			"output_format": outputFormat,
+			"fields":        fields,
+		},
+	})
+}
+
+// dataHash is the HMAC-SHA256 of a render payload. Unlike a plain hash it
+// cannot be confirmed by hashing a guess at the personal data it covers.
+func (t *TemplateUseCase) dataHash(payload []byte) string {
+	mac := hmac.New(sha256.New, t.dataHashKey)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// recordAudit appends a template change to the audit trail. The change is
+// already committed when it is recorded, so a failure is only logged by the
+// repository rather than reported to the caller.
+func (t *TemplateUseCase) recordAudit(ctx context.Context, action entity.AuditAction, ent *entity.Template, version int, dataHash string, details entity.JSONMap) {
+	// Scheduled purges run across tenants; the entry belongs to the
+	// template's own tenant.
+	ctx = tenant.WithID(ctx, ent.TenantID)
+
+	actor := audit.ActorFromContext(ctx)
+	templateID := ent.ID
+	t.auditRepository.CreateAuditLog(ctx, &entity.AuditLog{
+		Actor:           actor.Subject,
+		Action:          action,
+		TemplateID:      &templateID,
+		TemplateVersion: version,
+		IP:              actor.IP,
+		UserAgent:       actor.UserAgent,
+		DataHash:        dataHash,
+		Details:         details,
+	})
+}
@@ -0,0 +1,348 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/IlhamSetiaji/report-converter/dto"
+	"github.com/IlhamSetiaji/report-converter/entity"
+	"github.com/IlhamSetiaji/report-converter/logger"
+	"github.com/IlhamSetiaji/report-converter/repository"
+	"github.com/IlhamSetiaji/report-converter/request"
+	"github.com/IlhamSetiaji/report-converter/signer"
+	"github.com/IlhamSetiaji/report-converter/storage"
+	"github.com/google/uuid"
+)
+
+// memoryTemplateRepository keeps a single tenant's templates in memory. It
+// implements the part of ITemplateRepository the lifecycle uses; any other
+// method panics on the nil embedded interface.
+type memoryTemplateRepository struct {
+	repository.ITemplateRepository
+	templates map[uuid.UUID]*entity.Template
+	versions  map[uuid.UUID][]entity.TemplateVersion
+}
+
+func newMemoryTemplateRepository() *memoryTemplateRepository {
+	return &memoryTemplateRepository{
+		templates: map[uuid.UUID]*entity.Template{},
+		versions:  map[uuid.UUID][]entity.TemplateVersion{},
+	}
+}
+
+func (r *memoryTemplateRepository) FindTemplateByID(ctx context.Context, id uuid.UUID) (*entity.Template, error) {
+	template, ok := r.templates[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *template
+	return &copied, nil
+}
+
+func (r *memoryTemplateRepository) FindTemplateVersion(ctx context.Context, id uuid.UUID, version int) (*entity.TemplateVersion, error) {
+	for _, v := range r.versions[id] {
+		if v.Version == version {
+			return &v, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryTemplateRepository) CreateTemplateVersion(ctx context.Context, id uuid.UUID, path string, checksum string, content string) (*entity.Template, error) {
+	template, ok := r.templates[id]
+	if !ok {
+		return nil, nil
+	}
+	version := entity.TemplateVersion{TemplateID: id, Version: len(r.versions[id]) + 1, Path: path, Checksum: checksum}
+	r.versions[id] = append(r.versions[id], version)
+	template.CurrentVersion, template.Path, template.Content = version.Version, version.Path, content
+	if template.Status == entity.TemplateStatusInReview || template.Status == entity.TemplateStatusPublished {
+		template.Status = entity.TemplateStatusDraft
+	}
+	return r.FindTemplateByID(ctx, id)
+}
+
+func (r *memoryTemplateRepository) UpdateCurrentVersion(ctx context.Context, id uuid.UUID, version *entity.TemplateVersion, content string) (*entity.Template, error) {
+	template, ok := r.templates[id]
+	if !ok {
+		return nil, nil
+	}
+	template.CurrentVersion, template.Path, template.Content = version.Version, version.Path, content
+	return r.FindTemplateByID(ctx, id)
+}
+
+func (r *memoryTemplateRepository) UpdateTemplateStatus(ctx context.Context, id uuid.UUID, from []entity.TemplateStatus, updates map[string]interface{}) (*entity.Template, bool, error) {
+	template, ok := r.templates[id]
+	if !ok {
+		return nil, false, nil
+	}
+	for _, status := range from {
+		if template.Status == status {
+			template.Status = updates["status"].(entity.TemplateStatus)
+			ent, err := r.FindTemplateByID(ctx, id)
+			return ent, true, err
+		}
+	}
+	ent, err := r.FindTemplateByID(ctx, id)
+	return ent, false, err
+}
+
+func (r *memoryTemplateRepository) PublishTemplate(ctx context.Context, id uuid.UUID, approvedBy string, approvedAt time.Time) (*entity.Template, bool, error) {
+	template, ok := r.templates[id]
+	if !ok {
+		return nil, false, nil
+	}
+	if template.Status != entity.TemplateStatusInReview {
+		ent, err := r.FindTemplateByID(ctx, id)
+		return ent, false, err
+	}
+	template.Status = entity.TemplateStatusPublished
+	template.ApprovedBy, template.ApprovedAt = approvedBy, &approvedAt
+	template.PublishedVersion = template.CurrentVersion
+	for i, v := range r.versions[id] {
+		if v.Version == template.CurrentVersion && v.PublishedAt == nil {
+			r.versions[id][i].PublishedAt = &approvedAt
+		}
+	}
+	ent, err := r.FindTemplateByID(ctx, id)
+	return ent, true, err
+}
+
+func (r *memoryTemplateRepository) FindTemplateVersions(ctx context.Context, id uuid.UUID) ([]entity.TemplateVersion, error) {
+	return r.versions[id], nil
+}
+
+func (r *memoryTemplateRepository) DeleteTemplateByID(ctx context.Context, id uuid.UUID) error {
+	delete(r.templates, id)
+	return nil
+}
+
+type discardAuditRepository struct {
+	repository.IAuditRepository
+}
+
+func (discardAuditRepository) CreateAuditLog(ctx context.Context, log *entity.AuditLog) error {
+	return nil
+}
+
+func newTestTemplateUseCase(t *testing.T) (ITemplateUseCase, *memoryTemplateRepository, storage.Storage) {
+	t.Helper()
+
+	conf := &config.Config{
+		Server:   &config.Server{Url: "http://localhost"},
+		Download: &config.Download{SigningKey: "0123456789abcdef0123456789abcdef", Expiry: time.Minute},
+		Audit:    &config.Audit{HashKey: "fedcba9876543210fedcba9876543210"},
+	}
+	urlSigner := signer.NewHMACSigner(conf)
+	templateDTO := dto.NewTemplateDTO(*conf, logger.NewLogger(conf), urlSigner)
+	repo := newMemoryTemplateRepository()
+	store := storage.NewLocalStorage(t.TempDir())
+	return NewTemplateUseCase(repo, discardAuditRepository{}, templateDTO, store, urlSigner, conf), repo, store
+}
+
+func putTemplateFile(t *testing.T, store storage.Storage, key string, content string) {
+	t.Helper()
+	if err := store.Put(context.Background(), key, bytes.NewBufferString(content), int64(len(content)), "text/html"); err != nil {
+		t.Fatalf("put %s: %v", key, err)
+	}
+}
+
+func TestReplacedPublishedTemplateRendersOnlyAfterApproval(t *testing.T) {
+	ctx := context.Background()
+	uc, repo, store := newTestTemplateUseCase(t)
+
+	putTemplateFile(t, store, "storage/templates/invoice-v1.html", "<p>v1</p>")
+	putTemplateFile(t, store, "storage/templates/invoice-v2.html", "<p>v2</p>")
+
+	id := uuid.New()
+	repo.templates[id] = &entity.Template{
+		ID:               id,
+		Name:             "invoice",
+		TemplateType:     entity.TemplateType("html"),
+		Path:             "storage/templates/invoice-v1.html",
+		OutputFormat:     "pdf",
+		CurrentVersion:   1,
+		PublishedVersion: 1,
+		Status:           entity.TemplateStatusPublished,
+	}
+	publishedAt := time.Now()
+	repo.versions[id] = []entity.TemplateVersion{
+		{TemplateID: id, Version: 1, Path: "storage/templates/invoice-v1.html", PublishedAt: &publishedAt},
+	}
+
+	replaced, err := uc.ReplaceTemplateFile(ctx, id.String(), &request.ReplaceTemplateFileRequest{Path: "storage/templates/invoice-v2.html"})
+	if err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	if replaced.Status != string(entity.TemplateStatusDraft) {
+		t.Errorf("status after replace = %s, want draft", replaced.Status)
+	}
+
+	rendered, err := uc.ResolveTemplate(ctx, id.String(), true)
+	if err != nil {
+		t.Fatalf("resolve published: %v", err)
+	}
+	if rendered.PathOriginal != "storage/templates/invoice-v1.html" || rendered.Version != 1 {
+		t.Errorf("generate renders %s (version %d) before approval, want version 1", rendered.PathOriginal, rendered.Version)
+	}
+
+	preview, err := uc.ResolveTemplate(ctx, id.String(), false)
+	if err != nil {
+		t.Fatalf("resolve preview: %v", err)
+	}
+	if preview.PathOriginal != "storage/templates/invoice-v2.html" {
+		t.Errorf("preview renders %s, want the new working copy", preview.PathOriginal)
+	}
+
+	if _, err := uc.ResolveTemplate(ctx, id.String()+"@2", true); err == nil {
+		t.Error("generate rendered a pinned version that was never approved")
+	}
+
+	if _, err := uc.SubmitTemplate(ctx, id.String()); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	rendered, err = uc.ResolveTemplate(ctx, id.String(), true)
+	if err != nil {
+		t.Fatalf("resolve published in review: %v", err)
+	}
+	if rendered.Version != 1 {
+		t.Errorf("generate renders version %d while in review, want 1", rendered.Version)
+	}
+
+	if _, err := uc.ApproveTemplate(ctx, id.String(), &request.ReviewTemplateRequest{ReviewedBy: "reviewer"}); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	rendered, err = uc.ResolveTemplate(ctx, id.String(), true)
+	if err != nil {
+		t.Fatalf("resolve published after approval: %v", err)
+	}
+	if rendered.PathOriginal != "storage/templates/invoice-v2.html" || rendered.Version != 2 {
+		t.Errorf("generate renders %s (version %d) after approval, want version 2", rendered.PathOriginal, rendered.Version)
+	}
+}
+
+func TestNeverPublishedTemplateDoesNotRender(t *testing.T) {
+	ctx := context.Background()
+	uc, repo, _ := newTestTemplateUseCase(t)
+
+	id := uuid.New()
+	repo.templates[id] = &entity.Template{
+		ID:             id,
+		Name:           "draft",
+		Path:           "storage/templates/draft.html",
+		CurrentVersion: 1,
+		Status:         entity.TemplateStatusDraft,
+	}
+
+	if _, err := uc.ResolveTemplate(ctx, id.String(), true); err == nil {
+		t.Fatal("resolved a template that was never published")
+	}
+	if _, err := uc.ResolveTemplate(ctx, id.String(), false); err != nil {
+		t.Fatalf("preview of a draft: %v", err)
+	}
+}
+
+func TestPinnedVersionRendersOnceItWasPublished(t *testing.T) {
+	ctx := context.Background()
+	uc, repo, _ := newTestTemplateUseCase(t)
+
+	id := uuid.New()
+	publishedAt := time.Now()
+	repo.templates[id] = &entity.Template{
+		ID:               id,
+		Name:             "report",
+		Path:             "storage/templates/report-v3.html",
+		CurrentVersion:   3,
+		PublishedVersion: 3,
+		Status:           entity.TemplateStatusPublished,
+	}
+	repo.versions[id] = []entity.TemplateVersion{
+		{TemplateID: id, Version: 1, Path: "storage/templates/report-v1.html", PublishedAt: &publishedAt},
+		{TemplateID: id, Version: 2, Path: "storage/templates/report-v2.html"},
+		{TemplateID: id, Version: 3, Path: "storage/templates/report-v3.html", PublishedAt: &publishedAt},
+	}
+
+	issued, err := uc.ResolveTemplate(ctx, id.String()+"@1", true)
+	if err != nil {
+		t.Fatalf("resolve version 1: %v", err)
+	}
+	if issued.PathOriginal != "storage/templates/report-v1.html" {
+		t.Errorf("version 1 renders %s", issued.PathOriginal)
+	}
+
+	if _, err := uc.ResolveTemplate(ctx, id.String()+"@2", true); err == nil {
+		t.Error("generate rendered version 2, which was never published")
+	}
+	if _, err := uc.ResolveTemplate(ctx, id.String()+"@2", false); err != nil {
+		t.Errorf("preview of version 2: %v", err)
+	}
+}
+
+// stuckStorage fails every move, as a backend that is briefly unavailable
+// after the database commit would.
+func TestRollbackRestoresVersionContent(t *testing.T) {
+	ctx := context.Background()
+	uc, repo, store := newTestTemplateUseCase(t)
+
+	putTemplateFile(t, store, "storage/templates/invoice-v1.html", "<p>v1</p>")
+	putTemplateFile(t, store, "storage/templates/invoice-v2.html", "<p>v2</p>")
+
+	id := uuid.New()
+	repo.templates[id] = &entity.Template{
+		ID:             id,
+		Name:           "invoice",
+		TemplateType:   entity.TemplateType("html"),
+		Path:           "storage/templates/invoice-v1.html",
+		OutputFormat:   "pdf",
+		CurrentVersion: 1,
+		Status:         entity.TemplateStatusDraft,
+	}
+	repo.versions[id] = []entity.TemplateVersion{
+		{TemplateID: id, Version: 1, Path: "storage/templates/invoice-v1.html"},
+	}
+
+	if _, err := uc.ReplaceTemplateFile(ctx, id.String(), &request.ReplaceTemplateFileRequest{Path: "storage/templates/invoice-v2.html"}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	replacedContent := repo.templates[id].Content
+
+	rolledBack, err := uc.RollbackTemplate(ctx, id.String(), &request.RollbackTemplateRequest{Version: 1})
+	if err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if rolledBack.Version != 1 {
+		t.Errorf("current version after rollback = %d, want 1", rolledBack.Version)
+	}
+	if content := repo.templates[id].Content; content == "" || content == replacedContent {
+		t.Errorf("content after rollback = %q, want the content of version 1", content)
+	}
+}
+
+type stuckStorage struct {
+	storage.Storage
+}
+
+func (stuckStorage) Move(ctx context.Context, src string, dst string) error {
+	return errors.New("storage unavailable")
+}
+
+func TestDeleteSucceedsWhenFilesCannotBeMoved(t *testing.T) {
+	ctx := context.Background()
+	uc, repo, store := newTestTemplateUseCase(t)
+	uc.(*TemplateUseCase).storage = stuckStorage{store}
+
+	id := uuid.New()
+	repo.templates[id] = &entity.Template{ID: id, Name: "invoice", Path: "storage/templates/invoice.html", CurrentVersion: 1}
+	repo.versions[id] = []entity.TemplateVersion{{TemplateID: id, Version: 1, Path: "storage/templates/invoice.html"}}
+
+	if err := uc.DeleteTemplateByID(ctx, id.String()); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, ok := repo.templates[id]; ok {
+		t.Error("template row was not deleted")
+	}
+}
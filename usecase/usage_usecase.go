@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/IlhamSetiaji/report-converter/entity"
+	"github.com/IlhamSetiaji/report-converter/repository"
+	"github.com/IlhamSetiaji/report-converter/request"
+	"github.com/IlhamSetiaji/report-converter/response"
+	"github.com/google/uuid"
+)
+
+var usageCSVHeader = []string{
+	"tenant_id", "period", "period_start", "renders", "pages", "output_bytes", "cpu_millis", "wall_millis",
+}
+
+// RenderUsage is what one document generation consumed.
+type RenderUsage struct {
+	TemplateID      string
+	TemplateVersion int
+	Engine          string
+	OutputFormat    string
+	Preview         bool
+	PageCount       int
+	OutputBytes     int64
+	CPUTime         time.Duration
+	WallTime        time.Duration
+}
+
+type IUsageUseCase interface {
+	RecordRender(ctx context.Context, usage *RenderUsage) error
+	AggregateUsage(ctx context.Context, req *request.UsageReportRequest) ([]*response.UsageResponse, error)
+	ExportUsage(ctx context.Context, req *request.UsageReportRequest, w io.Writer) error
+}
+
+type UsageUseCase struct {
+	usageRepository repository.IUsageRepository
+}
+
+func NewUsageUseCase(usageRepository repository.IUsageRepository) IUsageUseCase {
+	return &UsageUseCase{
+		usageRepository: usageRepository,
+	}
+}
+
+func (u *UsageUseCase) RecordRender(ctx context.Context, usage *RenderUsage) error {
+	templateID, err := uuid.Parse(usage.TemplateID)
+	if err != nil {
+		return err
+	}
+
+	return u.usageRepository.CreateUsageRecord(ctx, &entity.UsageRecord{
+		TemplateID:      templateID,
+		TemplateVersion: usage.TemplateVersion,
+		Engine:          usage.Engine,
+		OutputFormat:    usage.OutputFormat,
+		Preview:         usage.Preview,
+		PageCount:       usage.PageCount,
+		OutputBytes:     usage.OutputBytes,
+		CPUMillis:       usage.CPUTime.Milliseconds(),
+		WallMillis:      usage.WallTime.Milliseconds(),
+	})
+}
+
+// AggregateUsage sums usage per tenant and day or month. Previews are left
+// out unless requested since they are not charged.
+func (u *UsageUseCase) AggregateUsage(ctx context.Context, req *request.UsageReportRequest) ([]*response.UsageResponse, error) {
+	filter := &repository.UsageFilter{
+		Period:         req.Period,
+		IncludePreview: req.IncludePreview,
+	}
+	if filter.Period == "" {
+		filter.Period = "day"
+	}
+
+	loc, _ := time.LoadLocation("Asia/Jakarta")
+	if req.From != "" {
+		from, err := time.ParseInLocation(time.DateOnly, req.From, loc)
+		if err != nil {
+			return nil, err
+		}
+		filter.From = &from
+	}
+	if req.To != "" {
+		to, err := time.ParseInLocation(time.DateOnly, req.To, loc)
+		if err != nil {
+			return nil, err
+		}
+		// to is inclusive of the whole day.
+		to = to.AddDate(0, 0, 1)
+		filter.To = &to
+	}
+
+	aggregates, err := u.usageRepository.AggregateUsage(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*response.UsageResponse, 0, len(aggregates))
+	for _, a := range aggregates {
+		responses = append(responses, &response.UsageResponse{
+			TenantID:    a.TenantID,
+			Period:      filter.Period,
+			PeriodStart: a.PeriodStart.Format(time.DateOnly),
+			Renders:     a.Renders,
+			Pages:       a.Pages,
+			OutputBytes: a.OutputBytes,
+			CPUMillis:   a.CPUMillis,
+			WallMillis:  a.WallMillis,
+		})
+	}
+	return responses, nil
+}
+
+// ExportUsage writes the aggregated usage to w as CSV or, when req.Format is
+// "json", as a JSON array.
+func (u *UsageUseCase) ExportUsage(ctx context.Context, req *request.UsageReportRequest, w io.Writer) error {
+	usage, err := u.AggregateUsage(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if req.Format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(usage)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(usageCSVHeader); err != nil {
+		return err
+	}
+	for _, row := range usage {
+		err := writer.Write([]string{
+			row.TenantID,
+			row.Period,
+			row.PeriodStart,
+			strconv.FormatInt(row.Renders, 10),
+			strconv.FormatInt(row.Pages, 10),
+			strconv.FormatInt(row.OutputBytes, 10),
+			strconv.FormatInt(row.CPUMillis, 10),
+			strconv.FormatInt(row.WallMillis, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
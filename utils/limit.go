@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrLimitExceeded is returned by a LimitedReader whose source holds more
+// than its limit.
+var ErrLimitExceeded = errors.New("read limit exceeded")
+
+// LimitedReader reads from R like io.LimitedReader, but fails with
+// ErrLimitExceeded when R holds more than N bytes instead of ending early, so
+// a truncated stream is never mistaken for the whole. N is the number of
+// bytes left.
+type LimitedReader struct {
+	R io.Reader
+	N int64
+}
+
+// LimitReader returns a reader that fails once more than n bytes are read
+// from r. Use it wherever the size of r is declared by the uploader, such as
+// a decompressed zip entry.
+func LimitReader(r io.Reader, n int64) *LimitedReader {
+	return &LimitedReader{R: r, N: n}
+}
+
+func (l *LimitedReader) Read(p []byte) (int, error) {
+	if l.N < 0 {
+		return 0, ErrLimitExceeded
+	}
+	// Read one byte past the limit to tell a source of exactly N bytes from
+	// a longer one.
+	if int64(len(p)) > l.N+1 {
+		p = p[:l.N+1]
+	}
+	n, err := l.R.Read(p)
+	if int64(n) > l.N {
+		n, l.N = int(l.N), -1
+		return n, ErrLimitExceeded
+	}
+	l.N -= int64(n)
+	return n, err
+}
@@ -10,11 +10,35 @@ type Meta struct {
 	Code    int    `json:"code"`
 	Status  string `json:"status"`
 	Message string `json:"message"`
+	// ErrorCode is the machine-readable code of a failed request.
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+type Pagination struct {
+	Page       int   `json:"page"`
+	Limit      int   `json:"limit"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
 }
 
 type Response struct {
-	Meta Meta        `json:"meta"`
-	Data interface{} `json:"data,omitempty"`
+	Meta       Meta        `json:"meta"`
+	Data       interface{} `json:"data,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+func NewPagination(page int, limit int, total int64) *Pagination {
+	totalPages := 0
+	if limit > 0 {
+		totalPages = int((total + int64(limit) - 1) / int64(limit))
+	}
+
+	return &Pagination{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: totalPages,
+	}
 }
 
 func FormatResponse(c *gin.Context, code int, status string, message string, data interface{}) {
@@ -39,6 +63,19 @@ func ErrorResponse(c *gin.Context, code int, status string, message string) {
 	})
 }
 
+// FailureResponse writes a failed request with its machine-readable code.
+func FailureResponse(c *gin.Context, code int, status string, message string, errorCode string, data interface{}) {
+	c.JSON(code, Response{
+		Meta: Meta{
+			Code:      code,
+			Status:    status,
+			Message:   message,
+			ErrorCode: errorCode,
+		},
+		Data: data,
+	})
+}
+
 func BadRequestResponse(c *gin.Context, message string, data interface{}) {
 	c.JSON(http.StatusBadRequest, Response{
 		Meta: Meta{
@@ -53,3 +90,15 @@ func BadRequestResponse(c *gin.Context, message string, data interface{}) {
 func SuccessResponse(c *gin.Context, code int, message string, data interface{}) {
 	FormatResponse(c, code, "success", message, data)
 }
+
+func PaginatedResponse(c *gin.Context, code int, message string, data interface{}, pagination *Pagination) {
+	c.JSON(code, Response{
+		Meta: Meta{
+			Code:    code,
+			Status:  "success",
+			Message: message,
+		},
+		Data:       data,
+		Pagination: pagination,
+	})
+}
@@ -1,7 +1,14 @@
 package validator
 
-import "github.com/go-playground/validator/v10"
+import (
+	"github.com/IlhamSetiaji/report-converter/response"
+	"github.com/go-playground/validator/v10"
+)
 
 type Validator interface {
 	GetValidator() *validator.Validate
+	// Translate lists the field errors in err in the language of
+	// acceptLanguage, an Accept-Language header. It returns nil when err is
+	// not a validation failure.
+	Translate(err error, acceptLanguage string) []*response.FieldErrorResponse
 }
@@ -0,0 +1,37 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/IlhamSetiaji/report-converter/request"
+)
+
+func TestOutputFormatFollowsTemplateType(t *testing.T) {
+	validate := NewValidatorV10(&config.Config{}).GetValidator()
+
+	for _, tc := range []struct {
+		templateType string
+		format       string
+		ok           bool
+	}{
+		{"docx", "docx", true},
+		{"docx", "pdf", true},
+		{"docx", "xlsx", false},
+		{"excel", "xlsx", true},
+		{"excel", "docx", false},
+	} {
+		filter := request.TemplateListRequest{TemplateType: tc.templateType, OutputFormat: tc.format}
+		if err := validate.Struct(filter); (err == nil) != tc.ok {
+			t.Errorf("%s as %s: err = %v, want ok %v", tc.templateType, tc.format, err, tc.ok)
+		}
+	}
+
+	// Without a template type any registered format passes.
+	if err := validate.Struct(request.TemplateListRequest{OutputFormat: "xlsx"}); err != nil {
+		t.Errorf("xlsx without a template type: %v", err)
+	}
+	if err := validate.Var("html", "output_format"); err == nil {
+		t.Error("html accepted although no template type renders it")
+	}
+}
@@ -1,30 +1,185 @@
 package validator
 
 import (
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
 	"github.com/IlhamSetiaji/report-converter/config"
+	"github.com/IlhamSetiaji/report-converter/entity"
+	"github.com/IlhamSetiaji/report-converter/rbac"
+	"github.com/IlhamSetiaji/report-converter/response"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/id"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	idTranslations "github.com/go-playground/validator/v10/translations/id"
 )
 
 type validatorV10 struct {
 	ValidatorV10 *validator.Validate
+	translators  *ut.UniversalTranslator
+}
+
+// customMessages are the translations of the validation tags this service
+// registers itself, by locale.
+var customMessages = map[string]map[string]string{
+	"en": {
+		"template_type": "{0} must be a supported template type",
+		"output_format": "{0} must be an output format the template type supports",
+		"role":          "{0} must be a known role",
+		"permission":    "{0} must be a known permission",
+	},
+	"id": {
+		"template_type": "{0} harus berupa tipe template yang didukung",
+		"output_format": "{0} harus berupa format keluaran yang didukung tipe template",
+		"role":          "{0} harus berupa peran yang dikenal",
+		"permission":    "{0} harus berupa izin yang dikenal",
+	},
 }
 
 func NewValidatorV10(conf *config.Config) Validator {
 	validate := validator.New()
 	validate.RegisterValidation("template_type", func(fl validator.FieldLevel) bool {
-		templateType := fl.Field().String()
-		switch templateType {
-		case "excel", "pdf":
-			return true
-		default:
-			return false
+		_, ok := entity.LookupTemplateType(fl.Field().String())
+		return ok
+	})
+	validate.RegisterValidation("output_format", validOutputFormat)
+	validate.RegisterValidation("role", func(fl validator.FieldLevel) bool {
+		return rbac.IsRole(fl.Field().String())
+	})
+	validate.RegisterValidation("permission", func(fl validator.FieldLevel) bool {
+		return rbac.IsPermission(fl.Field().String())
+	})
+
+	// Report fields by the name clients send them under.
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
 		}
+		return field.Name
 	})
+
+	// English is the fallback for languages without translations.
+	english := en.New()
+	translators := ut.New(english, english, id.New())
+	enTrans, _ := translators.GetTranslator("en")
+	idTrans, _ := translators.GetTranslator("id")
+	enTranslations.RegisterDefaultTranslations(validate, enTrans)
+	idTranslations.RegisterDefaultTranslations(validate, idTrans)
+	for _, trans := range []ut.Translator{enTrans, idTrans} {
+		registerCustomTranslations(validate, trans)
+	}
+
 	return &validatorV10{
 		ValidatorV10: validate,
+		translators:  translators,
+	}
+}
+
+// validOutputFormat checks an output format against the type registry. The
+// optional parameter names the sibling field holding the template type; when
+// it is set to a known type, the format must be one that type supports,
+// otherwise any registered type must support it.
+func validOutputFormat(fl validator.FieldLevel) bool {
+	format := fl.Field().String()
+	if fl.Param() != "" {
+		if field := reflect.Indirect(fl.Parent()).FieldByName(fl.Param()); field.IsValid() && field.Kind() == reflect.String {
+			if spec, ok := entity.LookupTemplateType(field.String()); ok {
+				return spec.SupportsOutputFormat(format)
+			}
+		}
 	}
+	return entity.IsOutputFormat(format)
 }
 
 func (v *validatorV10) GetValidator() *validator.Validate {
 	return v.ValidatorV10
 }
+
+func (v *validatorV10) Translate(err error, acceptLanguage string) []*response.FieldErrorResponse {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
+
+	trans, _ := v.translators.FindTranslator(preferredLocales(acceptLanguage)...)
+	fieldErrors := make([]*response.FieldErrorResponse, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		fieldErrors = append(fieldErrors, &response.FieldErrorResponse{
+			Field:   fieldError.Field(),
+			Rule:    fieldError.Tag(),
+			Message: fieldError.Translate(trans),
+		})
+	}
+	return fieldErrors
+}
+
+func registerCustomTranslations(validate *validator.Validate, trans ut.Translator) {
+	for tag, message := range customMessages[trans.Locale()] {
+		validate.RegisterTranslation(tag, trans,
+			func(trans ut.Translator) error {
+				return trans.Add(tag, message, true)
+			},
+			func(trans ut.Translator, fieldError validator.FieldError) string {
+				translated, err := trans.T(tag, fieldError.Field())
+				if err != nil {
+					return fieldError.Error()
+				}
+				return translated
+			},
+		)
+	}
+}
+
+// preferredLocales orders the languages of an Accept-Language header by
+// weight. Regional tags such as id-ID are followed by their base language,
+// which is what the translators are registered under.
+func preferredLocales(acceptLanguage string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	var languages []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		locale, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if locale == "" || locale == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		languages = append(languages, weighted{locale: strings.ToLower(locale), q: q})
+	}
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].q > languages[j].q
+	})
+
+	locales := make([]string, 0, 2*len(languages))
+	for _, language := range languages {
+		locale := strings.ReplaceAll(language.locale, "-", "_")
+		locales = append(locales, locale)
+		if base, _, found := strings.Cut(locale, "_"); found {
+			locales = append(locales, base)
+		}
+	}
+	return locales
+}